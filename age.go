@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// agedTask is a task with its age, for ?include=age. The age is derived
+// from CreatedAt when responding and is never stored.
+type agedTask struct {
+	Task
+
+	// Age is empty for tasks saved before CreatedAt existed.
+	Age string `json:"age,omitempty"`
+}
+
+// parseIncludeAge reports whether ?include= asks for the derived age
+// field, which only the JSON formats have room for.
+func parseIncludeAge(queryParams url.Values, format responseFormat) (bool, error) {
+	value := queryParams.Get("include")
+	if value == "" {
+		return false, nil
+	}
+	for _, name := range strings.Split(value, ",") {
+		if strings.TrimSpace(name) != "age" {
+			msg := fmt.Sprintf("Invalid include %q, must be age", value)
+			return false, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+	}
+	if format != formatJSON && format != formatNDJSON {
+		msg := fmt.Sprintf("include=age is only supported for json and ndjson, not %s", format.name)
+		return false, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+	return true, nil
+}
+
+func withAge(task Task, now time.Time) agedTask {
+	aged := agedTask{Task: task}
+	if !task.CreatedAt.IsZero() {
+		aged.Age = formatAge(now.Sub(task.CreatedAt))
+	}
+	return aged
+}
+
+// renderAgedTasks is renderTasks for ?include=age, in JSON or NDJSON.
+func renderAgedTasks(w io.Writer, format responseFormat, tasks []Task, now time.Time) error {
+	aged := make([]agedTask, len(tasks))
+	for index, task := range tasks {
+		aged[index] = withAge(task, now)
+	}
+
+	if format == formatNDJSON {
+		enc := json.NewEncoder(w)
+		for _, task := range aged {
+			err := enc.Encode(task)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	agedJson, err := json.Marshal(aged)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(agedJson)
+	return err
+}
+
+// formatAge describes a duration in its largest whole unit, like "3 days"
+// or "1 month", rounding down. Months are 30 days and years 365.
+func formatAge(age time.Duration) string {
+	const day = 24 * time.Hour
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * day},
+		{"month", 30 * day},
+		{"week", 7 * day},
+		{"day", day},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, unit := range units {
+		count := int(age / unit.size)
+		switch {
+		case count == 1:
+			return "1 " + unit.name
+		case count > 1:
+			return fmt.Sprintf("%d %ss", count, unit.name)
+		}
+	}
+	return "less than a minute"
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{30 * time.Second, "less than a minute"},
+		{time.Minute, "1 minute"},
+		{59 * time.Minute, "59 minutes"},
+		{25 * time.Hour, "1 day"},
+		{13 * 24 * time.Hour, "1 week"},
+		{45 * 24 * time.Hour, "1 month"},
+		{800 * 24 * time.Hour, "2 years"},
+	}
+	for _, test := range tests {
+		if got := formatAge(test.age); got != test.want {
+			t.Errorf("formatAge(%v) = %q, want %q", test.age, got, test.want)
+		}
+	}
+}
+
+func TestIncludeAge(t *testing.T) {
+	app, clock := newTestApp(t)
+	createTask(t, app, `{"Title":"Buy milk"}`)
+	clock.Advance(3 * time.Hour)
+	createTask(t, app, `{"Title":"Buy eggs"}`)
+	clock.Advance(2 * 24 * time.Hour)
+
+	rec := do(app, "GET", "/tasks?include=age", "")
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []agedTask
+	decode(t, rec, &tasks)
+	if len(tasks) != 2 || tasks[0].Age != "2 days" || tasks[1].Age != "2 days" {
+		t.Errorf("got %+v, want both 2 days old", tasks)
+	}
+
+	clock.Advance(-46 * time.Hour)
+	rec = do(app, "GET", "/tasks/1?include=age", "")
+	expectStatus(t, rec, http.StatusOK)
+	var task agedTask
+	decode(t, rec, &task)
+	if task.Age != "5 hours" || task.Title != "Buy milk" {
+		t.Errorf("got %+v, want Buy milk at 5 hours old", task)
+	}
+
+	rec = do(app, "GET", "/tasks/1?include=age&format=ndjson", "")
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), `"age":"5 hours"`) {
+		t.Errorf("ndjson body %s has no age", rec.Body.String())
+	}
+
+	// The age isn't saved with the task.
+	rec = do(app, "GET", "/tasks/1", "")
+	if strings.Contains(rec.Body.String(), `"age"`) {
+		t.Errorf("body %s has an age without include=age", rec.Body.String())
+	}
+
+	expectStatus(t, do(app, "GET", "/tasks?include=age&format=csv", ""), http.StatusBadRequest)
+	expectStatus(t, do(app, "GET", "/tasks/1?include=everything", ""), http.StatusBadRequest)
+}
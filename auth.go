@@ -47,6 +47,6 @@ func (app *application) basicAuth(next http.HandlerFunc) http.HandlerFunc {
 		// header to inform the client that we expect them to use basic
 		// authentication and send a 401 Unauthorized response.
 		w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
 	})
 }
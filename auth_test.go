@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasicAuthRejectsWrongCredentials(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	for name, setAuth := range map[string]func(req *http.Request){
+		"wrong password": func(req *http.Request) { req.SetBasicAuth("test", "wrong") },
+		"wrong username": func(req *http.Request) { req.SetBasicAuth("wrong", "test") },
+		"no credentials": func(req *http.Request) {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/tasks", nil)
+			setAuth(req)
+			rec := serve(app, req)
+			expectStatus(t, rec, http.StatusUnauthorized)
+			if challenge := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(challenge, `Basic realm="restricted"`) {
+				t.Errorf("got WWW-Authenticate %q, want a Basic challenge for realm restricted", challenge)
+			}
+		})
+	}
+
+	expectStatus(t, do(app, "GET", "/tasks", ""), http.StatusOK)
+}
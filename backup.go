@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const backupPrefix = "tasks-"
+
+// backupTasks copies every task in store into a new timestamped folder
+// under backupDir, then deletes the oldest backups so that at most keep
+// remain. It returns the path of the new backup.
+func backupTasks(store TaskStore, backupDir string, keep int, now time.Time) (string, error) {
+	err := os.MkdirAll(backupDir, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	// The timestamp format sorts lexically in time order, which is what
+	// pruneBackups relies on.
+	dest := filepath.Join(backupDir, backupPrefix+now.UTC().Format("20060102T150405.000Z"))
+	err = os.Mkdir(dest, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	// Task files are copied byte for byte, so files the store skipped as
+	// invalid are kept too; other stores are written out one <id>.json
+	// per task, the layout POST /admin/restore reads.
+	if fileStore, ok := store.(*FileStore); ok {
+		err = copyTaskFiles(fileStore.dir, dest)
+	} else {
+		err = writeTaskFiles(store, dest)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return dest, pruneBackups(backupDir, keep)
+}
+
+func copyTaskFiles(tasksDir, dest string) error {
+	entries, err := os.ReadDir(tasksDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		contents, err := os.ReadFile(filepath.Join(tasksDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(dest, entry.Name()), contents, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTaskFiles(store TaskStore, dest string) error {
+	tasks, err := store.List()
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		taskJson, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(dest, fmt.Sprintf("%d.json", task.Id)), taskJson, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pruneBackups(backupDir string, keep int) error {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), backupPrefix) {
+			backups = append(backups, entry.Name())
+		}
+	}
+	slices.Sort(backups)
+
+	for len(backups) > keep {
+		err = os.RemoveAll(filepath.Join(backupDir, backups[0]))
+		if err != nil {
+			return fmt.Errorf("pruning backup %s: %w", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBackupCopiesTaskFilesAsIs(t *testing.T) {
+	tasksDir := t.TempDir()
+	files := map[string]string{
+		"1.json": "{\n  \"Id\": 1,\n  \"Title\": \"Water plants\"\n}",
+		"2.json": "not json",
+	}
+	for name, contents := range files {
+		err := os.WriteFile(filepath.Join(tasksDir, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	captureLogs(t)
+	store, err := newFileStore(tasksDir, osFS{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := backupTasks(store, t.TempDir(), 5, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range files {
+		backedUp, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(backedUp) != contents {
+			t.Errorf("backed up %s as %q, want %q", name, backedUp, contents)
+		}
+	}
+}
+
+func TestBackupWritesSQLiteTasks(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	_, err := store.Create([]Task{{Title: "Water plants"}, {Title: "Feed cat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := backupTasks(store, t.TempDir(), 5, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"1.json", "2.json"} {
+		contents, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = validateBackupEntry(name, contents)
+		if err != nil {
+			t.Errorf("backup of %s can't be restored: %v", name, err)
+		}
+	}
+}
+
+func TestBackupPrunesOldest(t *testing.T) {
+	store, err := newFileStore(t.TempDir(), osFS{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupDir := t.TempDir()
+
+	var made []string
+	for minute := 0; minute < 4; minute++ {
+		dest, err := backupTasks(store, backupDir, 2, testNow.Add(time.Duration(minute)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		made = append(made, filepath.Base(dest))
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, entry := range entries {
+		kept = append(kept, entry.Name())
+	}
+	if !slices.Equal(kept, made[2:]) {
+		t.Errorf("kept backups %v, want the newest two of %v", kept, made)
+	}
+}
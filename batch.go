@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// batchResult is the outcome for one task in a batch operation that can
+// partly succeed: an HTTP status for the task, with an error if it failed
+// or the task as it now is if it didn't.
+type batchResult struct {
+	Id     int    `json:"id"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Task   *Task  `json:"task,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// writeBatchResponse replies with every result, as 207 Multi-Status if any
+// task failed and 200 if none did, so clients that only check the status
+// still see partial failures.
+func writeBatchResponse(w http.ResponseWriter, res batchResponse) {
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while saving your tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	status := http.StatusOK
+	for _, result := range res.Results {
+		if result.Status >= 300 {
+			status = http.StatusMultiStatus
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(resJson)
+}
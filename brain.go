@@ -1,20 +1,27 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
-	"path"
-	"path/filepath"
-	"slices"
+	"os/signal"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/time/rate"
 )
 
 type application struct {
@@ -22,34 +29,168 @@ type application struct {
 		username string
 		password string
 	}
+	rootRedirect string
+	logSample    float64
+	staticPath   string
+	assets       fs.FS
+	rateWatcher  *rateWatcher
+	rateLimiter  *rateLimiter
+	headers      map[string]string
+	clock        Clock
+	location     *time.Location
+	files        taskFS
+	store        TaskStore
+	sessions     *sessionStore
+	focusLists   *focusStore
+
+	// storeFallback is why BRAIN_STORE couldn't be opened, when
+	// BRAIN_STORE_FALLBACK put the file store in its place.
+	storeFallback error
+
+	// createMu serializes creates and restores, from backups or the trash,
+	// so the task count checked against BRAIN_TASK_LIMIT_HARD still holds
+	// when the tasks are stored.
+	createMu sync.Mutex
+
+	// updateMu serializes changes to existing tasks, and creates that check
+	// a parent, so an If-Match precondition or a parent check still holds
+	// when the task is written. Take createMu first when holding both.
+	updateMu sync.Mutex
+
+	lockCompleted   bool
+	putStrict       bool
+	safeDelete      bool
+	hideSubtasks    bool
+	normalizeTitle  bool
+	capitalizeTitle bool
+	titleMaxLength  int
+	titleMaxWords   int
+	maxSubtaskDepth int
+	taskLimit       limit
+	tagLimit        limit
+
+	settings serverSettings
 }
 
 type Task struct {
 	Id        int
 	Title     string
 	Completed bool
+	Color     string
+	Priority  string
+	StartDate *time.Time
+	Due       *time.Time
+	Tags      []string
+
+	// ParentId makes the task a subtask of another. GET
+	// /tasks/{id}/subtasks lists a task's children.
+	ParentId *int
+
+	// CreatedAt and UpdatedAt are set by the server; values in request
+	// bodies are ignored. Tasks saved before they existed have zero times.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// DeletedAt is set when a task is moved to the trash. Trashed tasks are
+	// hidden everywhere except ?include_deleted=true on list.
+	DeletedAt *time.Time
 }
 
+// newTaskBody is a task in a POST /tasks body, which may give its due date
+// as due_text, like "tomorrow 5pm", rather than a timestamp in Due.
+type newTaskBody struct {
+	Task
+	DueText *string `json:"due_text"`
+}
+
+// JsonTask is a PUT or PATCH body. Following JSON Merge Patch (RFC 7386),
+// a field set to a value changes, a field set to null is cleared and a
+// missing field is left alone. A nil pointer can't tell the last two
+// apart, so decodeTaskChanges also records which fields were present.
+// Tags are the exception: null leaves them alone, as it always has, and
+// [] clears them.
 type JsonTask struct {
 	Id        *int
 	Title     *string
 	Completed *bool
+	Color     *string
+	Priority  *string
+	StartDate *time.Time
+	Due       *time.Time
+	Tags      *[]string
+	ParentId  *int
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+
+	// DueText sets Due from text like "next monday", see parseDueText.
+	DueText *string `json:"due_text"`
+
+	present map[string]bool
 }
 
-const tasksPath = "tasks"
+// has reports whether the body included field, whether as a value or null.
+func (changes JsonTask) has(field string) bool {
+	return changes.present[field]
+}
 
-func main() {
-	err := os.Mkdir(tasksPath, 0750)
-	if err != nil && !os.IsExist(err) {
-		log.Fatal(err)
+// valueOf returns *value, or the zero value for nil, which is what a null
+// in a merge patch clears a field to.
+func valueOf[T any](value *T) T {
+	if value == nil {
+		var zero T
+		return zero
 	}
+	return *value
+}
+
+// shutdownTimeout bounds how long in-flight requests get to finish once a
+// shutdown signal arrives.
+const shutdownTimeout = 10 * time.Second
 
-	err = godotenv.Load()
+func main() {
+	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
 	app := new(application)
+	app.clock = systemClock{}
+
+	// Calendar days, like "due today", are in the server's timezone, which
+	// TZ sets.
+	app.location = time.Local
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	if portNumber, err := strconv.Atoi(port); err != nil || portNumber < 1 || portNumber > 65535 {
+		log.Fatal("PORT must be a port number between 1 and 65535")
+	}
+
+	// TLS_ENABLED=false serves plain HTTP, which saves making and trusting
+	// certificates for local development.
+	tlsEnabled := os.Getenv("TLS_ENABLED") != "false"
+	tlsCert := os.Getenv("TLS_CERT")
+	if tlsCert == "" {
+		tlsCert = "./localhost.pem"
+	}
+	tlsKey := os.Getenv("TLS_KEY")
+	if tlsKey == "" {
+		tlsKey = "./localhost-key.pem"
+	}
+	app.settings.port = port
+	app.settings.tlsEnabled = tlsEnabled
+	app.settings.tlsCert = tlsCert
+	app.settings.tlsKey = tlsKey
+	if tlsEnabled {
+		for _, file := range []string{tlsCert, tlsKey} {
+			_, err = os.Stat(file)
+			if err != nil {
+				log.Fatalf("TLS certificate files must exist: %s", err.Error())
+			}
+		}
+	}
 
 	app.auth.username = os.Getenv("AUTH_USERNAME")
 	app.auth.password = os.Getenv("AUTH_PASSWORD")
@@ -62,220 +203,1448 @@ func main() {
 		log.Fatal("basic auth password must be provided")
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", app.basicAuth(welcome))
-	mux.HandleFunc("/tasks", app.basicAuth(tasks))
-	mux.HandleFunc("/tasks/", app.basicAuth(task))
+	app.rootRedirect = os.Getenv("BRAIN_ROOT_REDIRECT")
+
+	app.logSample = 1
+	if value := os.Getenv("BRAIN_LOG_SAMPLE"); value != "" {
+		app.logSample, err = strconv.ParseFloat(value, 64)
+		if err != nil || app.logSample < 0 || app.logSample > 1 {
+			log.Fatal("BRAIN_LOG_SAMPLE must be a number from 0 to 1")
+		}
+	}
+
+	app.lockCompleted = os.Getenv("BRAIN_LOCK_COMPLETED") == "true"
+	app.putStrict = os.Getenv("BRAIN_PUT_STRICT") == "true"
+	app.safeDelete = os.Getenv("BRAIN_SAFE_DELETE") == "true"
+	app.hideSubtasks = os.Getenv("BRAIN_HIDE_SUBTASKS") == "true"
+	app.normalizeTitle = os.Getenv("BRAIN_NORMALIZE_TITLE") == "true"
+	app.capitalizeTitle = os.Getenv("BRAIN_CAPITALIZE_TITLE") == "true"
+	app.titleMaxLength = 500
+	if value := os.Getenv("BRAIN_TITLE_MAX_LENGTH"); value != "" {
+		app.titleMaxLength, err = strconv.Atoi(value)
+		if err != nil || app.titleMaxLength < 1 {
+			log.Fatal("BRAIN_TITLE_MAX_LENGTH must be a positive integer")
+		}
+	}
+	if value := os.Getenv("BRAIN_TITLE_MAX_WORDS"); value != "" {
+		app.titleMaxWords, err = strconv.Atoi(value)
+		if err != nil || app.titleMaxWords < 1 {
+			log.Fatal("BRAIN_TITLE_MAX_WORDS must be a positive integer")
+		}
+	}
+	if value := os.Getenv("BRAIN_MAX_SUBTASK_DEPTH"); value != "" {
+		app.maxSubtaskDepth, err = strconv.Atoi(value)
+		if err != nil || app.maxSubtaskDepth < 1 {
+			log.Fatal("BRAIN_MAX_SUBTASK_DEPTH must be a positive integer")
+		}
+	}
+
+	app.taskLimit = limit{name: "Task count", status: http.StatusConflict}
+	app.tagLimit = limit{name: "Tag count", status: http.StatusBadRequest}
+	for _, bound := range []struct {
+		env string
+		dst *int
+	}{
+		{"BRAIN_TASK_LIMIT_SOFT", &app.taskLimit.soft},
+		{"BRAIN_TASK_LIMIT_HARD", &app.taskLimit.hard},
+		{"BRAIN_TAG_LIMIT_SOFT", &app.tagLimit.soft},
+		{"BRAIN_TAG_LIMIT_HARD", &app.tagLimit.hard},
+	} {
+		if value := os.Getenv(bound.env); value != "" {
+			*bound.dst, err = strconv.Atoi(value)
+			if err != nil || *bound.dst < 1 {
+				log.Fatalf("%s must be a positive integer", bound.env)
+			}
+		}
+	}
+
+	sessionTTL := 24 * time.Hour
+	if value := os.Getenv("BRAIN_SESSION_TTL"); value != "" {
+		sessionTTL, err = time.ParseDuration(value)
+		if err != nil || sessionTTL <= 0 {
+			log.Fatal("BRAIN_SESSION_TTL must be a positive duration")
+		}
+	}
+	app.sessions = newSessionStore(sessionTTL)
+
+	retryAttempts := 3
+	if value := os.Getenv("BRAIN_FS_RETRIES"); value != "" {
+		retryAttempts, err = strconv.Atoi(value)
+		if err != nil || retryAttempts < 1 {
+			log.Fatal("BRAIN_FS_RETRIES must be a positive integer")
+		}
+	}
+	retryBackoff := 50 * time.Millisecond
+	if value := os.Getenv("BRAIN_FS_RETRY_BACKOFF"); value != "" {
+		retryBackoff, err = time.ParseDuration(value)
+		if err != nil || retryBackoff < 0 {
+			log.Fatal("BRAIN_FS_RETRY_BACKOFF must be a non-negative duration")
+		}
+	}
+	app.files = retryFS{fs: osFS{}, attempts: retryAttempts, backoff: retryBackoff}
+	app.settings.fsRetries = retryAttempts
+	app.settings.fsRetryBackoff = retryBackoff
+
+	// BRAIN_WRITE_BUFFER trades durability for fewer disk writes: updates
+	// are kept in memory and flushed at this interval and on shutdown.
+	var writeBuffer *bufferedFS
+	if value := os.Getenv("BRAIN_WRITE_BUFFER"); value != "" {
+		flushInterval, err := time.ParseDuration(value)
+		if err != nil || flushInterval < 0 {
+			log.Fatal("BRAIN_WRITE_BUFFER must be a non-negative duration")
+		}
+		if flushInterval > 0 {
+			writeBuffer = newBufferedFS(app.files)
+			app.files = writeBuffer
+			go writeBuffer.flushEvery(flushInterval)
+		}
+		app.settings.writeBuffer = flushInterval
+	}
+
+	// BRAIN_STORE picks where tasks are kept: files, one JSON file per
+	// task in TASKS_DIR, or sqlite, a database at BRAIN_SQLITE_PATH.
+	storeType := os.Getenv("BRAIN_STORE")
+	if storeType == "" {
+		storeType = "files"
+	}
+	if storeType != "files" && storeType != "sqlite" {
+		log.Fatal("BRAIN_STORE must be files or sqlite")
+	}
+	err = app.openStore(storeType, os.Getenv("BRAIN_STORE_FALLBACK") == "true")
+	if err != nil {
+		log.Fatalf("loading tasks: %s", err.Error())
+	}
+
+	focusPath := os.Getenv("BRAIN_FOCUS_PATH")
+	if focusPath == "" {
+		focusPath = defaultFocusPath
+	}
+	app.focusLists = &focusStore{path: focusPath, files: app.files}
+
+	warnRequests := 300
+	if value := os.Getenv("BRAIN_IP_WARN_REQUESTS"); value != "" {
+		warnRequests, err = strconv.Atoi(value)
+		if err != nil || warnRequests < 1 {
+			log.Fatal("BRAIN_IP_WARN_REQUESTS must be a positive integer")
+		}
+	}
+	warnWindow := time.Minute
+	if value := os.Getenv("BRAIN_IP_WARN_WINDOW"); value != "" {
+		warnWindow, err = time.ParseDuration(value)
+		if err != nil || warnWindow <= 0 {
+			log.Fatal("BRAIN_IP_WARN_WINDOW must be a positive duration")
+		}
+	}
+	app.rateWatcher = newRateWatcher(warnRequests, warnWindow)
+
+	// BRAIN_RATE_LIMIT is in requests per second per IP; 0 turns limiting
+	// off.
+	requestRate := 10.0
+	if value := os.Getenv("BRAIN_RATE_LIMIT"); value != "" {
+		requestRate, err = strconv.ParseFloat(value, 64)
+		if err != nil || requestRate < 0 {
+			log.Fatal("BRAIN_RATE_LIMIT must be a non-negative number")
+		}
+	}
+	requestBurst := 50
+	if value := os.Getenv("BRAIN_RATE_BURST"); value != "" {
+		requestBurst, err = strconv.Atoi(value)
+		if err != nil || requestBurst < 1 {
+			log.Fatal("BRAIN_RATE_BURST must be a positive integer")
+		}
+	}
+	if requestRate > 0 {
+		app.rateLimiter = newRateLimiter(rate.Limit(requestRate), requestBurst)
+	}
+
+	app.headers, err = parseSecurityHeaders(os.Getenv("BRAIN_SECURITY_HEADERS"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app.staticPath = os.Getenv("BRAIN_STATIC_PATH")
+	if app.staticPath == "" {
+		app.staticPath = defaultStaticPath
+	}
+	if !strings.HasSuffix(app.staticPath, "/") {
+		app.staticPath += "/"
+	}
+	app.assets, err = fs.Sub(staticFiles, "static")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if app.staticPath == "/" {
+		log.Fatal("BRAIN_STATIC_PATH must not be /, use BRAIN_ROOT_REDIRECT to send / to the front-end")
+	}
+	if route := staticPathRoute(app.apiRoutes(), app.staticPath); route != "/" {
+		log.Fatalf("BRAIN_STATIC_PATH must not be inside the API route %s, which it would take over (or crash on, if it is the same path)", route)
+	}
+
+	if os.Getenv("BRAIN_BACKUP_ON_START") == "true" {
+		backupDir := os.Getenv("BRAIN_BACKUP_DIR")
+		if backupDir == "" {
+			backupDir = "backups"
+		}
+		backupKeep := 5
+		if value := os.Getenv("BRAIN_BACKUP_KEEP"); value != "" {
+			backupKeep, err = strconv.Atoi(value)
+			if err != nil || backupKeep < 1 {
+				log.Fatal("BRAIN_BACKUP_KEEP must be a positive integer")
+			}
+		}
+
+		dest, err := backupTasks(app.store, backupDir, backupKeep, app.clock.Now())
+		if err != nil {
+			log.Fatalf("backing up tasks: %s", err.Error())
+		}
+		log.Printf("backed up tasks to %s", dest)
+
+		app.settings.backupOnStart = true
+		app.settings.backupDir = backupDir
+		app.settings.backupKeep = backupKeep
+	}
+
+	app.settings.otelEndpoint = os.Getenv("BRAIN_OTEL_ENDPOINT")
+	shutdownTracing, err := setupTracing(context.Background(), app.settings.otelEndpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
 
 	srv := &http.Server{
-		Addr:         ":8080",
-		Handler:      mux,
+		Addr:         ":" + port,
+		Handler:      app.routes(),
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+	app.settings.readTimeout = srv.ReadTimeout
+	app.settings.writeTimeout = srv.WriteTimeout
+	app.settings.idleTimeout = srv.IdleTimeout
+
+	// On SIGINT or SIGTERM stop accepting connections and let in-flight
+	// requests finish their writes before flushing any buffered updates.
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		<-shutdown
+		log.Print("shutting down")
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			log.Printf("shutting down server: %s", err.Error())
+		}
+
+		if writeBuffer != nil {
+			err = writeBuffer.Flush()
+			if err != nil {
+				log.Fatalf("flushing buffered writes: %s", err.Error())
+			}
+		}
+		close(stopped)
+	}()
+
+	if tlsEnabled {
+		log.Printf("starting server with TLS on %s", srv.Addr)
+		err = srv.ListenAndServeTLS(tlsCert, tlsKey)
+	} else {
+		log.Printf("starting server WITHOUT TLS on %s, for local development only", srv.Addr)
+		err = srv.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-stopped
+}
+
+// openStore opens the storeType store as app.store. With fallback set, a
+// store other than files that fails to open is replaced by the file store,
+// so a misconfigured database doesn't keep the server down, and /healthz
+// reports the failure until it is fixed.
+func (app *application) openStore(storeType string, fallback bool) error {
+	var err error
+	if storeType == "sqlite" {
+		sqlitePath := os.Getenv("BRAIN_SQLITE_PATH")
+		if sqlitePath == "" {
+			sqlitePath = "brain.db"
+		}
+		app.settings.sqlitePath = sqlitePath
+		app.store, err = openSQLiteStore(sqlitePath)
+		if err == nil {
+			app.settings.storeType = storeType
+			return nil
+		}
+		if !fallback {
+			return err
+		}
+		log.Printf("WARNING: opening the %s store failed, falling back to the file store: %s", storeType, err.Error())
+		app.storeFallback = fmt.Errorf("the %s store failed to open, using the file store: %w", storeType, err)
+	}
+
+	tasksDir := os.Getenv("TASKS_DIR")
+	if tasksDir == "" {
+		tasksDir = "tasks"
+	}
+	err = os.Mkdir(tasksDir, 0750)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	storePretty := os.Getenv("BRAIN_STORE_PRETTY") == "true"
+	app.store, err = newFileStore(tasksDir, app.files, storePretty)
+	app.settings.storeType = "files"
+	app.settings.tasksDir = tasksDir
+	app.settings.storePretty = storePretty
+	return err
+}
+
+// routes registers every handler and wraps them in the middleware each
+// request passes through.
+func (app *application) routes() http.Handler {
+	mux := app.apiRoutes()
+
+	// The API routes are more specific than any static prefix main allows,
+	// so ServeMux always routes API requests first.
+	if static, ok := staticHandler(app.assets, app.staticPath); ok {
+		mux.HandleFunc(app.staticPath, app.basicAuth(static.ServeHTTP))
+		log.Printf("serving front-end at %s", app.staticPath)
+	} else {
+		log.Print("no front-end assets embedded, static UI disabled")
+	}
+
+	return app.logRequests(app.watchRequestRate(app.securityHeaders(app.limitRequests(app.trackSession(traceRequests(mux))))))
+}
+
+// apiRoutes registers every handler other than the static front-end.
+func (app *application) apiRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", app.basicAuth(app.welcome))
+	mux.HandleFunc("/tasks", app.basicAuth(app.tasks))
+	mux.HandleFunc("/tasks/", app.basicAuth(app.task))
+	mux.HandleFunc("/tasks/batch-get", app.basicAuth(app.batchGet))
+	mux.HandleFunc("/tasks/ids", app.basicAuth(app.ids))
+	mux.HandleFunc("/tasks/buckets", app.basicAuth(app.dueBuckets))
+	mux.HandleFunc("/tasks/schedule", app.basicAuth(app.schedule))
+	mux.HandleFunc("/tasks/tags/stats", app.basicAuth(app.tagStats))
+	mux.HandleFunc("/tasks/import/validate", app.basicAuth(app.validateImport))
+	mux.HandleFunc("/tasks/import/markdown", app.basicAuth(app.importMarkdown))
+	mux.HandleFunc("/tasks/export", app.basicAuth(app.export))
+	mux.HandleFunc("/tasks/schema", app.basicAuth(schema))
+	mux.HandleFunc("/focus", app.basicAuth(app.focus))
+	mux.HandleFunc("/focus/", app.basicAuth(app.focusTask))
+	mux.HandleFunc("/admin/restore", app.basicAuth(app.restore))
+	mux.HandleFunc("/admin/config", app.basicAuth(app.adminConfig))
 
-	log.Printf("starting server on %s", srv.Addr)
-	err = srv.ListenAndServeTLS("./localhost.pem", "./localhost-key.pem")
-	log.Fatal(err)
+	// Probes can't be expected to know the password.
+	mux.HandleFunc("/healthz", app.healthz)
+
+	return mux
 }
 
-func welcome(w http.ResponseWriter, r *http.Request) {
+func (app *application) welcome(w http.ResponseWriter, r *http.Request) {
+	// ServeMux sends every unmatched path to "/", so anything else here is
+	// an unknown route.
+	if r.URL.Path != "/" {
+		msg := fmt.Sprintf("No route for %v", r.URL.Path)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
+
+	if app.rootRedirect != "" {
+		http.Redirect(w, r, app.rootRedirect, http.StatusFound)
+		return
+	}
 	fmt.Fprintf(w, "Welcome to Brain!")
 }
 
-func tasks(w http.ResponseWriter, r *http.Request) {
+func (app *application) tasks(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
-		create(w, r)
+		app.create(w, r)
 
 	case "GET":
-		list(w, r)
+		app.list(w, r)
 
 	default:
 		msg := fmt.Sprintf("Unsupported request method %v to /tasks", r.Method)
 		log.Print(msg)
-		http.Error(w, msg, http.StatusNotImplemented)
+		w.Header().Set("Allow", "GET, POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
 	}
 }
 
-func create(w http.ResponseWriter, r *http.Request) {
-	var task Task
-	err := decodeJsonBody(w, r, &task)
+func (app *application) create(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		msg := fmt.Sprintf("Could not read request body, %q", err.Error())
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	// A top-level array creates several tasks at once.
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		app.createMany(w, r)
+		return
+	}
+
+	var newTask newTaskBody
+	err = decodeJsonBody(w, r, &newTask)
 	if err != nil {
 		var mr *malformedRequest
 		if errors.As(err, &mr) {
-			http.Error(w, mr.msg, mr.status)
+			writeJSONError(w, mr.status, mr.msg)
 		} else {
 			log.Print(err.Error())
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		}
 		return
 	}
+	task, err := app.applyDueText(newTask.Task, newTask.DueText)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	// TODO: secy: validate/sanitize input?
+	// Holding updateMu as well keeps the parent checked in prepareTask from
+	// being deleted before the task is stored.
+	app.createMu.Lock()
+	defer app.createMu.Unlock()
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
 
-	nextId, err := getNextId()
+	task, err = app.prepareTask(w, task)
 	if err != nil {
-		msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
-		http.Error(w, msg, http.StatusInternalServerError)
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+		}
+		return
+	}
+
+	err = app.checkTaskCount(w, 1)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+		}
 		return
 	}
-	task.Id = nextId
 
-	jsonTask, err := json.Marshal(task)
+	created, err := app.store.Create([]Task{task})
 	if err != nil {
 		msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
-		http.Error(w, msg, http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, msg)
 		return
 	}
+	task = created[0]
+	app.sessions.touch(sessionId(r), task.Id, app.clock.Now())
 
-	path := taskPath(task.Id)
-	err = os.WriteFile(path, jsonTask, 0644)
+	taskJson, err := json.Marshal(task)
 	if err != nil {
 		msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
-		http.Error(w, msg, http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, msg)
 		return
 	}
 
-	fmt.Fprintf(w, "Task: %+v", task)
+	w.Header().Set("Location", fmt.Sprintf("/tasks/%d", task.Id))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(taskJson)
 }
 
-func getNextId() (int, error) {
-	files, err := filepath.Glob(taskPath("*"))
+// createMany creates every task in a JSON array, or none of them if any
+// is malformed, and responds with the created tasks and their IDs.
+func (app *application) createMany(w http.ResponseWriter, r *http.Request) {
+	var bodies []newTaskBody
+	err := decodeJsonBody(w, r, &bodies)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			log.Print(err.Error())
+			writeJSONError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		return
+	}
+	if len(bodies) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "Request body must contain at least one task")
+		return
+	}
+	tasks := make([]Task, len(bodies))
+	for index, body := range bodies {
+		tasks[index], err = app.applyDueText(body.Task, body.DueText)
+		if err != nil {
+			msg := fmt.Sprintf("Task at index %d: %s", index, err.Error())
+			writeJSONError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
+	app.createMu.Lock()
+	defer app.createMu.Unlock()
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
 
-	ids := make([]int, len(files))
-	for index, file := range files {
-		filename := path.Base(file)
-		id, err := strconv.Atoi(strings.Split(filename, ".")[0])
+	for index := range tasks {
+		tasks[index], err = app.prepareTask(w, tasks[index])
 		if err != nil {
-			return 0, err
+			var mr *malformedRequest
+			if errors.As(err, &mr) {
+				msg := fmt.Sprintf("Task at index %d: %s", index, mr.msg)
+				writeJSONError(w, mr.status, msg)
+			} else {
+				msg := fmt.Sprintf("An error occurred while saving your tasks, %q", err.Error())
+				writeJSONError(w, http.StatusInternalServerError, msg)
+			}
+			return
+		}
+	}
+
+	err = app.checkTaskCount(w, len(tasks))
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			msg := fmt.Sprintf("An error occurred while saving your tasks, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
 		}
-		ids[index] = id
+		return
+	}
+
+	tasks, err = app.store.Create(tasks)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while saving your tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	for _, task := range tasks {
+		app.sessions.touch(sessionId(r), task.Id, app.clock.Now())
+	}
+
+	tasksJson, err := json.Marshal(tasks)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while saving your tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(tasksJson)
+}
+
+// prepareTask applies the normalization and checks every new task goes
+// through. Soft limit warnings are added to w's headers.
+func (app *application) prepareTask(w http.ResponseWriter, task Task) (Task, error) {
+	var err error
+	task.Color, err = normalizeColor(task.Color)
+	if err != nil {
+		return task, err
+	}
+	task.Priority, err = normalizePriority(task.Priority)
+	if err != nil {
+		return task, err
+	}
+
+	if app.normalizeTitle {
+		task.Title = normalizeTitle(task.Title, app.capitalizeTitle)
+	}
+	err = app.validateTask(task)
+	if err != nil {
+		return task, err
+	}
+	task.Tags = normalizeTags(task.Tags)
+	err = app.tagLimit.check(w, len(task.Tags))
+	if err != nil {
+		return task, err
+	}
+	err = app.checkParent(task)
+	if err != nil {
+		return task, err
+	}
+	now := app.clock.Now()
+	task.CreatedAt, task.UpdatedAt = now, now
+	task.DeletedAt = nil
+
+	return task, nil
+}
+
+// validateTask rejects tasks whose title is blank or over the configured
+// length in characters or words.
+func (app *application) validateTask(task Task) error {
+	if strings.TrimSpace(task.Title) == "" {
+		return &malformedRequest{status: http.StatusBadRequest, msg: "Title must not be empty"}
 	}
-	slices.Sort(ids)
 
-	nextId := 1
-	if len(ids) > 0 {
-		nextId = ids[len(ids)-1] + 1
+	length := utf8.RuneCountInString(task.Title)
+	if app.titleMaxLength > 0 && length > app.titleMaxLength {
+		msg := fmt.Sprintf("Title has %d characters, must have at most %d", length, app.titleMaxLength)
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
 	}
 
-	return nextId, err
+	words := len(strings.Fields(task.Title))
+	if app.titleMaxWords > 0 && words > app.titleMaxWords {
+		msg := fmt.Sprintf("Title has %d words, must have at most %d", words, app.titleMaxWords)
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	return nil
+}
+
+// checkTaskCount applies the task count limit to adding more tasks. Tasks
+// in the trash don't count, so deleting a task frees its slot.
+func (app *application) checkTaskCount(w http.ResponseWriter, adding int) error {
+	if app.taskLimit.soft == 0 && app.taskLimit.hard == 0 {
+		return nil
+	}
+
+	tasks, err := app.store.List()
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, task := range tasks {
+		if task.DeletedAt == nil {
+			count++
+		}
+	}
+	return app.taskLimit.check(w, count+adding)
 }
 
-func list(w http.ResponseWriter, r *http.Request) {
-	files, err := filepath.Glob(taskPath("*"))
+func (app *application) list(w http.ResponseWriter, r *http.Request) {
+	query, err := app.parseTaskQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// BRAIN_HIDE_SUBTASKS keeps the main list to top-level tasks, which
+	// ?include_subtasks= overrides either way.
+	query.topLevel = app.hideSubtasks
+	switch value := r.URL.Query().Get("include_subtasks"); value {
+	case "":
+	case "true", "false":
+		query.topLevel = value == "false"
+	default:
+		msg := fmt.Sprintf("Invalid include_subtasks %q, must be true or false", value)
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	format, err := negotiateFormat(r)
+	if err != nil {
+		var mr *malformedRequest
+		errors.As(err, &mr)
+		writeJSONError(w, mr.status, mr.msg)
+		return
+	}
+
+	includeAge, err := parseIncludeAge(r.URL.Query(), format)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tasks, err := app.store.List()
 	if err != nil {
 		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
-		http.Error(w, msg, http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
 	}
 
-	queryParams := r.URL.Query()
-	search := queryParams.Get("q")
+	// Start from an empty slice rather than nil so that no matches
+	// encodes as [] instead of null.
+	matched := []Task{}
+	for _, task := range tasks {
+		if query.matches(task) {
+			matched = append(matched, task)
+		}
+	}
 
-	for _, file := range files {
-		taskJson, err := os.ReadFile(file)
-		if err != nil {
-			msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
-			http.Error(w, msg, http.StatusInternalServerError)
+	page := opts.page(matched)
+
+	// highlight=true marks up search matches in titles, which makes each
+	// title HTML rather than plain text.
+	if r.URL.Query().Get("highlight") == "true" && query.search != "" {
+		for index := range page {
+			page[index].Title = query.highlight(page[index].Title)
 		}
+	}
 
-		if len(search) != 0 {
-			var task Task
-			json.Unmarshal(taskJson, &task)
-			if !strings.Contains(task.Title, search) {
-				continue
-			}
+	var body bytes.Buffer
+	if includeAge {
+		err = renderAgedTasks(&body, format, page, app.clock.Now())
+	} else {
+		err = renderTasks(&body, format, page, app.clock.Now())
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// The total counts every match, not just this page, so clients can
+	// render "showing 1-50 of 340".
+	total := strconv.Itoa(len(matched))
+
+	// The ETag covers the rendered page and the total, so polling clients
+	// get a 304 until either changes.
+	etag := collectionETag(body.Bytes(), total)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("X-Total-Count", total)
+	w.Header().Set("Content-Range", contentRange(opts.offset, len(page), len(matched)))
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Write(body.Bytes())
+}
+
+// ids lists the IDs of matching tasks.
+func (app *application) ids(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/ids", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	query, err := app.parseTaskQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tasks, err := app.store.List()
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	matched := []int{}
+	for _, task := range tasks {
+		if query.matches(task) {
+			matched = append(matched, task.Id)
 		}
+	}
 
-		fmt.Fprintf(w, string(taskJson))
+	idsJson, err := json.Marshal(matched)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(idsJson)
 }
 
-func task(w http.ResponseWriter, r *http.Request) {
-	taskId, err := strconv.Atoi(path.Base(r.URL.Path))
+func (app *application) task(w http.ResponseWriter, r *http.Request) {
+	idPart, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/tasks/"), "/")
+	taskId, err := strconv.Atoi(idPart)
 	if err != nil {
-		msg := fmt.Sprintf("Invalid task ID: %v", path.Base(r.URL.Path))
-		http.Error(w, msg, http.StatusBadRequest)
+		msg := fmt.Sprintf("Invalid task ID: %v", idPart)
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	switch action {
+	case "":
+	case "restore":
+		if r.Method != "POST" {
+			msg := fmt.Sprintf("Unsupported request method %v to /tasks/{id}/restore", r.Method)
+			log.Print(msg)
+			w.Header().Set("Allow", "POST")
+			writeJSONError(w, http.StatusMethodNotAllowed, msg)
+			return
+		}
+		app.untrash(w, r, taskId)
+		return
+	case "subtasks":
+		if r.Method != "GET" {
+			msg := fmt.Sprintf("Unsupported request method %v to /tasks/{id}/subtasks", r.Method)
+			log.Print(msg)
+			w.Header().Set("Allow", "GET")
+			writeJSONError(w, http.StatusMethodNotAllowed, msg)
+			return
+		}
+		app.subtasks(w, r, taskId)
+		return
+	case "references":
+		if r.Method != "GET" {
+			msg := fmt.Sprintf("Unsupported request method %v to /tasks/{id}/references", r.Method)
+			log.Print(msg)
+			w.Header().Set("Allow", "GET")
+			writeJSONError(w, http.StatusMethodNotAllowed, msg)
+			return
+		}
+		app.references(w, r, taskId)
+		return
+	case "due":
+		if r.Method != "DELETE" {
+			msg := fmt.Sprintf("Unsupported request method %v to /tasks/{id}/due", r.Method)
+			log.Print(msg)
+			w.Header().Set("Allow", "DELETE")
+			writeJSONError(w, http.StatusMethodNotAllowed, msg)
+			return
+		}
+		app.clearDue(w, r, taskId)
+		return
+	default:
+		msg := fmt.Sprintf("No route for %v", r.URL.Path)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
 	}
 
 	switch r.Method {
 	case "GET":
-		show(w, r, taskId)
+		app.show(w, r, taskId)
 
 	case "PUT":
-		update(w, r, taskId)
+		app.update(w, r, taskId, true)
+
+	case "PATCH":
+		app.update(w, r, taskId, false)
 
 	case "DELETE":
-		delete(w, r, taskId)
+		app.remove(w, r, taskId)
 
 	default:
 		msg := fmt.Sprintf("Unsupported request method %v to /tasks/", r.Method)
 		log.Print(msg)
-		http.Error(w, msg, http.StatusNotImplemented)
+		w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+	}
+}
+
+func (app *application) show(w http.ResponseWriter, r *http.Request, taskId int) {
+	format, err := negotiateFormat(r)
+	if err != nil {
+		var mr *malformedRequest
+		errors.As(err, &mr)
+		writeJSONError(w, mr.status, mr.msg)
+		return
+	}
+
+	includeAge, err := parseIncludeAge(r.URL.Query(), format)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var expandSubtasks bool
+	switch value := r.URL.Query().Get("expand"); value {
+	case "":
+	case "subtasks":
+		if format != formatJSON {
+			msg := fmt.Sprintf("expand=subtasks is only supported for json, not %s", format.name)
+			writeJSONError(w, http.StatusBadRequest, msg)
+			return
+		}
+		expandSubtasks = true
+	default:
+		msg := fmt.Sprintf("Invalid expand %q, must be subtasks", value)
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	task, err := app.readTask(taskId)
+	if errors.Is(err, os.ErrNotExist) {
+		msg := fmt.Sprintf("Task with ID %v not found", taskId)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving your task, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// The age and subtasks are part of the body, so the ETag changes with
+	// them too.
+	var taskJson []byte
+	switch {
+	case expandSubtasks:
+		var tree taskTree
+		tree, err = app.subtaskTree(task, includeAge)
+		if err == nil {
+			taskJson, err = json.Marshal(tree)
+		}
+	case includeAge:
+		taskJson, err = json.Marshal(withAge(task, app.clock.Now()))
+	default:
+		taskJson, err = json.Marshal(task)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving your task, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	etag := taskETag(taskJson, format)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if format != formatJSON {
+		w.Header().Set("Content-Type", format.contentType)
+		if includeAge {
+			err = renderAgedTasks(w, format, []Task{task}, app.clock.Now())
+		} else {
+			err = renderTasks(w, format, []Task{task}, app.clock.Now())
+		}
+		if err != nil {
+			log.Print(err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(taskJson)
+}
+
+type batchGetRequest struct {
+	Ids    []int
+	Fields []string
+}
+
+type batchGetResponse struct {
+	Tasks   []map[string]json.RawMessage `json:"tasks"`
+	Missing []int                        `json:"missing"`
+}
+
+func (app *application) batchGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/batch-get", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	var req batchGetRequest
+	err := decodeJsonBody(w, r, &req)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			log.Print(err.Error())
+			writeJSONError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		return
+	}
+
+	for _, name := range req.Fields {
+		if !isTaskField(name) {
+			msg := fmt.Sprintf("Unknown task field %q", name)
+			writeJSONError(w, http.StatusBadRequest, msg)
+			return
+		}
 	}
+
+	res := batchGetResponse{
+		Tasks:   []map[string]json.RawMessage{},
+		Missing: []int{},
+	}
+	for _, taskId := range req.Ids {
+		taskJson, err := app.readTaskJson(taskId)
+		if errors.Is(err, os.ErrNotExist) {
+			res.Missing = append(res.Missing, taskId)
+			continue
+		}
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+
+		fields, err := projectFields(taskJson, req.Fields)
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		res.Tasks = append(res.Tasks, fields)
+	}
+
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resJson)
 }
 
-func show(w http.ResponseWriter, r *http.Request, taskId int) {
-	filename := taskPath(taskId)
-	task, err := os.ReadFile(filename)
+// projectFields returns only the requested top-level fields of a stored
+// task. Field names are matched case-insensitively, so "id" selects "Id".
+// An empty selection returns every field.
+func projectFields(taskJson []byte, selected []string) (map[string]json.RawMessage, error) {
+	var all map[string]json.RawMessage
+	err := json.Unmarshal(taskJson, &all)
 	if err != nil {
-		http.Error(w, "", http.StatusNotFound)
+		return nil, err
+	}
+
+	if len(selected) == 0 {
+		return all, nil
+	}
+
+	projected := make(map[string]json.RawMessage, len(selected))
+	for _, name := range selected {
+		for key, value := range all {
+			if strings.EqualFold(key, name) {
+				projected[key] = value
+				break
+			}
+		}
+	}
+
+	return projected, nil
+}
+
+func isTaskField(name string) bool {
+	fields := reflect.VisibleFields(reflect.TypeOf(Task{}))
+	for _, field := range fields {
+		if strings.EqualFold(field.Name, name) {
+			return true
+		}
 	}
-	fmt.Fprintf(w, string(task))
+	return false
 }
 
-func update(w http.ResponseWriter, r *http.Request, taskId int) {
-	var taskChanges JsonTask
-	err := decodeJsonBody(w, r, &taskChanges)
+// update changes a task. With replace, as for PUT, the body is the whole
+// task and omitted fields reset to their zero values, or are rejected with
+// BRAIN_PUT_STRICT; otherwise, as for
+// PATCH, the body is a merge patch: only the fields present in it change,
+// and those set to null are cleared.
+func (app *application) update(w http.ResponseWriter, r *http.Request, taskId int, replace bool) {
+	taskChanges, err := decodeTaskChanges(w, r)
 	if err != nil {
 		var mr *malformedRequest
 		if errors.As(err, &mr) {
-			http.Error(w, mr.msg, mr.status)
+			writeJSONError(w, mr.status, mr.msg)
 		} else {
 			log.Print(err.Error())
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 		}
 		return
 	}
 
-	// TODO: secy: validate/sanitize input?
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
 
-	filename := taskPath(taskId)
-	currentTaskJson, err := os.ReadFile(filename)
+	task, err := app.readTask(taskId)
+	if errors.Is(err, os.ErrNotExist) {
+		msg := fmt.Sprintf("Task with ID %v not found", taskId)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
 	if err != nil {
-		http.Error(w, "", http.StatusNotFound)
+		msg := fmt.Sprintf("An error occurred while retrieving your task, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	current := task
+
+	// If-Match rejects the update when the task has changed since the
+	// client read it, rather than overwriting someone else's edit.
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		currentJson, err := json.Marshal(current)
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		if !taskETagMatches(ifMatch, currentJson) {
+			msg := fmt.Sprintf("Task with ID %v has changed since it was read", taskId)
+			writeJSONError(w, http.StatusPreconditionFailed, msg)
+			return
+		}
+	}
+	if replace {
+		// BRAIN_PUT_STRICT makes PUT take a complete task, so a client
+		// that forgot a field gets an error rather than a reset field.
+		// Nullable fields may be sent as null.
+		if app.putStrict {
+			var missing []string
+			for _, field := range taskSchema() {
+				if !field.ReadOnly && !taskChanges.has(field.Name) {
+					missing = append(missing, field.Name)
+				}
+			}
+			if len(missing) > 0 {
+				msg := fmt.Sprintf("Request body is missing %s, PUT must include every writable field", strings.Join(missing, ", "))
+				writeJSONError(w, http.StatusBadRequest, msg)
+				return
+			}
+		}
+		if taskChanges.Title == nil {
+			msg := "Request body must include a Title to replace a task, use PATCH to change only some fields"
+			writeJSONError(w, http.StatusBadRequest, msg)
+			return
+		}
+		task = Task{Id: current.Id, Priority: defaultPriority, CreatedAt: current.CreatedAt}
+	}
+	if taskChanges.has("Title") {
+		task.Title = valueOf(taskChanges.Title)
+		if app.normalizeTitle {
+			task.Title = normalizeTitle(task.Title, app.capitalizeTitle)
+		}
+		err = app.validateTask(task)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if taskChanges.has("Completed") {
+		task.Completed = valueOf(taskChanges.Completed)
+	}
+	if taskChanges.has("Color") {
+		task.Color, err = normalizeColor(valueOf(taskChanges.Color))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if taskChanges.has("Priority") {
+		task.Priority, err = normalizePriority(valueOf(taskChanges.Priority))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if taskChanges.has("StartDate") {
+		task.StartDate = taskChanges.StartDate
+	}
+	if taskChanges.has("Due") {
+		task.Due = taskChanges.Due
+	}
+	if taskChanges.DueText != nil {
+		if taskChanges.has("Due") {
+			writeJSONError(w, http.StatusBadRequest, "Request body must not include both Due and due_text")
+			return
+		}
+		due, err := parseDueText(*taskChanges.DueText, app.clock.Now().In(app.location))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		task.Due = &due
+	}
+	if taskChanges.Tags != nil {
+		task.Tags = normalizeTags(*taskChanges.Tags)
+		err = app.tagLimit.check(w, len(task.Tags))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if taskChanges.has("ParentId") {
+		task.ParentId = taskChanges.ParentId
+		err = app.checkParent(task)
+		if err != nil {
+			var mr *malformedRequest
+			if errors.As(err, &mr) {
+				writeJSONError(w, mr.status, mr.msg)
+			} else {
+				msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+				writeJSONError(w, http.StatusInternalServerError, msg)
+			}
+			return
+		}
 	}
 
-	var task Task
-	json.Unmarshal(currentTaskJson, &task)
-	if taskChanges.Title != nil {
-		task.Title = *taskChanges.Title
+	// With BRAIN_LOCK_COMPLETED a completed task is a historical record:
+	// the only edit allowed is un-completing it, which unlocks it again.
+	if app.lockCompleted && current.Completed && task.Completed && !reflect.DeepEqual(task, current) {
+		msg := fmt.Sprintf("Task with ID %v is completed and locked, un-complete it before editing", taskId)
+		writeJSONError(w, http.StatusConflict, msg)
+		return
 	}
-	if taskChanges.Completed != nil {
-		task.Completed = *taskChanges.Completed
+	task.UpdatedAt = app.clock.Now()
+
+	// Save before responding: once a 200 is written a failed write can no
+	// longer be reported.
+	err = app.store.Update(task)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
 	}
+	app.sessions.touch(sessionId(r), task.Id, app.clock.Now())
 
-	updatedTaskJson, err := json.Marshal(task)
+	taskJson, err := json.Marshal(task)
 	if err != nil {
 		msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
-		http.Error(w, msg, http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, msg)
 		return
 	}
+	w.Header().Set("ETag", taskETag(taskJson, formatJSON))
+
+	responseJson := taskJson
+	if r.URL.Query().Get("return") == "changes" {
+		changes, err := changedFields(current, task)
+		if err == nil {
+			responseJson, err = json.Marshal(changes)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJson)
+}
+
+// decodeTaskChanges decodes a PUT or PATCH body into a JsonTask, noting
+// which of its fields the body included. JSON field names match
+// case-insensitively, as they do when decoding.
+func decodeTaskChanges(w http.ResponseWriter, r *http.Request) (JsonTask, error) {
+	var changes JsonTask
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		msg := fmt.Sprintf("Could not read request body, %q", err.Error())
+		return changes, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
 
-	fmt.Fprintf(w, "Task: %+v", task)
-	err = os.WriteFile(filename, updatedTaskJson, 0644)
+	err = decodeJsonBody(w, r, &changes)
+	if err != nil {
+		return changes, err
+	}
+
+	// The body decoded as a JsonTask, so it is an object (or null) whose
+	// keys are all JsonTask fields.
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(body, &fields)
+	if err != nil {
+		return changes, err
+	}
+	changes.present = map[string]bool{}
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(changes)) {
+		for key := range fields {
+			if field.IsExported() && strings.EqualFold(key, field.Name) {
+				changes.present[field.Name] = true
+			}
+		}
+	}
+
+	return changes, nil
 }
 
-func delete(w http.ResponseWriter, r *http.Request, taskId int) {
-	filename := taskPath(taskId)
-	err := os.Remove(filename)
+// remove moves a task to the trash by setting its DeletedAt. The task is
+// kept so POST /tasks/{id}/restore can bring it back.
+func (app *application) remove(w http.ResponseWriter, r *http.Request, taskId int) {
+	// With ?idempotent=true a missing task counts as already deleted, so
+	// clients can safely retry a DELETE whose response they never saw.
+	idempotent := r.URL.Query().Get("idempotent") == "true"
+
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
+
+	task, err := app.readTask(taskId)
+	if errors.Is(err, os.ErrNotExist) {
+		if idempotent {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		msg := fmt.Sprintf("Task with ID %v not found", taskId)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while deleting task with ID %v: %q", taskId, err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	children, err := app.children(taskId)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while deleting task with ID %v: %q", taskId, err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// BRAIN_SAFE_DELETE refuses to delete a parent of tasks that aren't in
+	// the trash unless ?force=true, so subtasks aren't moved by accident.
+	if app.safeDelete && r.URL.Query().Get("force") != "true" {
+		referencedBy := []int{}
+		for _, child := range children {
+			if child.DeletedAt == nil {
+				referencedBy = append(referencedBy, child.Id)
+			}
+		}
+		if len(referencedBy) > 0 {
+			writeReferencedError(w, taskId, referencedBy)
+			return
+		}
+	}
+
+	now := app.clock.Now()
+	task.DeletedAt = &now
+	task.UpdatedAt = now
+	err = app.store.Update(task)
 	if err != nil {
 		msg := fmt.Sprintf("An error occurred while deleting task with ID %v: %q", taskId, err.Error())
-		http.Error(w, msg, http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	app.sessions.touch(sessionId(r), task.Id, app.clock.Now())
+
+	// Subtasks aren't trashed along with their parent. They move up to
+	// its parent instead, or become top-level tasks, so nothing
+	// disappears that wasn't deleted. Restoring the parent doesn't bring
+	// them back under it.
+	for _, child := range children {
+		child.ParentId = task.ParentId
+		child.UpdatedAt = now
+		err = app.store.Update(child)
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while moving subtasks of task with ID %v: %q", taskId, err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// untrash restores a task from the trash and responds with it. The task
+// counts against BRAIN_TASK_LIMIT_HARD again once restored, so it is
+// checked like a create.
+func (app *application) untrash(w http.ResponseWriter, r *http.Request, taskId int) {
+	app.createMu.Lock()
+	defer app.createMu.Unlock()
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
+
+	task, err := app.store.Get(taskId)
+	if errors.Is(err, os.ErrNotExist) {
+		msg := fmt.Sprintf("Task with ID %v not found", taskId)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving your task, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	if task.DeletedAt == nil {
+		msg := fmt.Sprintf("Task with ID %v is not in the trash", taskId)
+		writeJSONError(w, http.StatusConflict, msg)
+		return
+	}
+	err = app.checkTaskCount(w, 1)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			msg := fmt.Sprintf("An error occurred while restoring task with ID %v: %q", taskId, err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+		}
+		return
+	}
+
+	task.DeletedAt = nil
+	task.UpdatedAt = app.clock.Now()
+	err = app.store.Update(task)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while restoring task with ID %v: %q", taskId, err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	app.sessions.touch(sessionId(r), task.Id, app.clock.Now())
+
+	taskJson, err := json.Marshal(task)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while restoring task with ID %v: %q", taskId, err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(taskJson)
+}
+
+// changedFields returns the fields whose values differ between before and
+// after, keyed by their JSON names, plus the task's Id so the client knows
+// which task the changes apply to.
+func changedFields(before, after Task) (map[string]json.RawMessage, error) {
+	beforeFields, err := taskFields(before)
+	if err != nil {
+		return nil, err
 	}
+	afterFields, err := taskFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]json.RawMessage{"Id": afterFields["Id"]}
+	for name, value := range afterFields {
+		if !bytes.Equal(beforeFields[name], value) {
+			changes[name] = value
+		}
+	}
+
+	return changes, nil
+}
+
+func taskFields(task Task) (map[string]json.RawMessage, error) {
+	taskJson, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(taskJson, &fields)
+	return fields, err
+}
+
+// readTask returns a task that isn't in the trash.
+func (app *application) readTask(taskId int) (Task, error) {
+	task, err := app.store.Get(taskId)
+	if err == nil && task.DeletedAt != nil {
+		return Task{}, fmt.Errorf("task %d is in the trash: %w", taskId, fs.ErrNotExist)
+	}
+	return task, err
 }
 
-func taskPath(taskId interface{}) string {
-	return fmt.Sprintf("%v/%v.json", tasksPath, taskId)
+// readTaskJson returns a task as compact JSON, so API responses look the
+// same whether or not the store writes pretty files.
+func (app *application) readTaskJson(taskId int) ([]byte, error) {
+	task, err := app.readTask(taskId)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(task)
 }
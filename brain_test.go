@@ -0,0 +1,747 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testNow is where every test app's clock starts.
+var testNow = time.Date(2024, time.March, 4, 9, 30, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	// Handlers log freely; tests that check logs capture them with
+	// captureLogs.
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newTestApp returns an application configured as main would with no
+// environment set, keeping its tasks in a temporary directory and reading
+// time from the returned fake clock.
+func newTestApp(t *testing.T) (*application, *fakeClock) {
+	t.Helper()
+
+	clock := newFakeClock(testNow)
+	app := new(application)
+	app.clock = clock
+	app.location = time.UTC
+	app.auth.username = "test"
+	app.auth.password = "test"
+	app.staticPath = defaultStaticPath
+	app.logSample = 1
+	app.titleMaxLength = 500
+	app.taskLimit = limit{name: "Task count", status: http.StatusConflict}
+	app.tagLimit = limit{name: "Tag count", status: http.StatusBadRequest}
+	app.sessions = newSessionStore(24 * time.Hour)
+	app.rateWatcher = newRateWatcher(300, time.Minute)
+	app.files = osFS{}
+
+	var err error
+	app.headers, err = parseSecurityHeaders("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.store, err = newFileStore(t.TempDir(), app.files, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.focusLists = &focusStore{path: filepath.Join(t.TempDir(), defaultFocusPath), files: app.files}
+
+	return app, clock
+}
+
+// newRequest returns a request to target carrying the test credentials.
+func newRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.SetBasicAuth("test", "test")
+	return req
+}
+
+// serve sends req through the app's routes and middleware.
+func serve(app *application, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	app.routes().ServeHTTP(rec, req)
+	return rec
+}
+
+// do is serve for a request built by newRequest.
+func do(app *application, method, target, body string) *httptest.ResponseRecorder {
+	return serve(app, newRequest(method, target, body))
+}
+
+// decode unmarshals a JSON response body into dst, failing the test if
+// it doesn't parse.
+func decode(t *testing.T, rec *httptest.ResponseRecorder, dst interface{}) {
+	t.Helper()
+
+	err := json.Unmarshal(rec.Body.Bytes(), dst)
+	if err != nil {
+		t.Fatalf("decoding response %q: %v", rec.Body.String(), err)
+	}
+}
+
+// createTask creates a task from a JSON body and returns it.
+func createTask(t *testing.T, app *application, body string) Task {
+	t.Helper()
+
+	rec := do(app, "POST", "/tasks", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("creating %s: status %d, body %s", body, rec.Code, rec.Body.String())
+	}
+	var task Task
+	decode(t, rec, &task)
+	return task
+}
+
+// expectStatus fails the test unless rec has status.
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if rec.Code != status {
+		t.Fatalf("got status %d, want %d, body %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+// captureLogs collects everything logged until the test ends.
+func captureLogs(t *testing.T) *strings.Builder {
+	t.Helper()
+
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(io.Discard) })
+	return &logs
+}
+
+func TestTimestampsComeFromClock(t *testing.T) {
+	app, clock := newTestApp(t)
+
+	task := createTask(t, app, `{"Title":"Water plants"}`)
+	if !task.CreatedAt.Equal(testNow) || !task.UpdatedAt.Equal(testNow) {
+		t.Fatalf("created with CreatedAt %v and UpdatedAt %v, want both %v", task.CreatedAt, task.UpdatedAt, testNow)
+	}
+
+	clock.Advance(time.Hour)
+	rec := do(app, "PATCH", "/tasks/1", `{"Completed":true}`)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if !task.CreatedAt.Equal(testNow) {
+		t.Errorf("CreatedAt changed to %v on update", task.CreatedAt)
+	}
+	if want := testNow.Add(time.Hour); !task.UpdatedAt.Equal(want) {
+		t.Errorf("UpdatedAt is %v, want %v", task.UpdatedAt, want)
+	}
+}
+
+// brokenStore is a TaskStore whose reads or writes fail with the given
+// errors, for checking how handlers report store failures.
+type brokenStore struct {
+	TaskStore
+	getErr    error
+	listErr   error
+	updateErr error
+}
+
+func (store brokenStore) Get(taskId int) (Task, error) {
+	if store.getErr != nil {
+		return Task{}, store.getErr
+	}
+	return store.TaskStore.Get(taskId)
+}
+
+func (store brokenStore) List() ([]Task, error) {
+	if store.listErr != nil {
+		return nil, store.listErr
+	}
+	return store.TaskStore.List()
+}
+
+func (store brokenStore) Update(task Task) error {
+	if store.updateErr != nil {
+		return store.updateErr
+	}
+	return store.TaskStore.Update(task)
+}
+
+func TestDeleteReportsReadErrors(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+	store := app.store
+	app.store = brokenStore{TaskStore: store, getErr: errors.New("disk on fire")}
+
+	rec := do(app, "DELETE", "/tasks/1", "")
+	expectStatus(t, rec, http.StatusInternalServerError)
+
+	tasks, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Id != 1 || tasks[0].DeletedAt != nil {
+		t.Errorf("store holds %+v after a failed delete, want only task 1 untouched", tasks)
+	}
+}
+
+func TestReadErrorsAreNotNotFound(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+	app.store = brokenStore{TaskStore: app.store, getErr: errors.New("database is locked")}
+
+	for _, test := range []struct{ method, target, body string }{
+		{"GET", "/tasks/1", ""},
+		{"PATCH", "/tasks/1", `{"Completed":true}`},
+		{"PUT", "/tasks/1", `{"Title":"Feed cat"}`},
+		{"POST", "/tasks/1/restore", ""},
+		{"GET", "/tasks/1/subtasks", ""},
+		{"GET", "/tasks/1/references", ""},
+	} {
+		rec := do(app, test.method, test.target, test.body)
+		if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "database is locked") {
+			t.Errorf("%s %s: got status %d, body %s, want a 500 with the read error", test.method, test.target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestPatchMergeSemantics(t *testing.T) {
+	due := "2024-03-08T17:00:00Z"
+	for _, test := range []struct {
+		field string
+		set   string
+		check func(task Task) bool
+	}{
+		{"Color", `"red"`, func(task Task) bool { return task.Color == "red" }},
+		{"Priority", `"high"`, func(task Task) bool { return task.Priority == "high" }},
+		{"StartDate", `"` + due + `"`, func(task Task) bool { return task.StartDate != nil }},
+		{"Due", `"` + due + `"`, func(task Task) bool { return task.Due != nil }},
+		{"ParentId", `1`, func(task Task) bool { return task.ParentId != nil }},
+		{"Completed", `true`, func(task Task) bool { return task.Completed }},
+	} {
+		t.Run(test.field, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			createTask(t, app, `{"Title":"Parent"}`)
+			task := createTask(t, app, `{"Title":"Water plants"}`)
+			target := fmt.Sprintf("/tasks/%d", task.Id)
+
+			rec := do(app, "PATCH", target, fmt.Sprintf(`{%q:%s}`, test.field, test.set))
+			expectStatus(t, rec, http.StatusOK)
+			decode(t, rec, &task)
+			if !test.check(task) {
+				t.Fatalf("setting %s gave %+v", test.field, task)
+			}
+
+			rec = do(app, "PATCH", target, `{"Title":"Water the plants"}`)
+			expectStatus(t, rec, http.StatusOK)
+			decode(t, rec, &task)
+			if !test.check(task) {
+				t.Fatalf("leaving %s out changed it: %+v", test.field, task)
+			}
+
+			rec = do(app, "PATCH", target, fmt.Sprintf(`{%q:null}`, test.field))
+			expectStatus(t, rec, http.StatusOK)
+			decode(t, rec, &task)
+			if test.check(task) {
+				t.Fatalf("setting %s to null didn't clear it: %+v", test.field, task)
+			}
+			if task.Title != "Water the plants" {
+				t.Errorf("clearing %s changed the title to %q", test.field, task.Title)
+			}
+		})
+	}
+}
+
+func TestPatchNullTagsAreKept(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants","Tags":["home"]}`)
+
+	rec := do(app, "PATCH", "/tasks/1", `{"Tags":null,"Completed":true}`)
+	expectStatus(t, rec, http.StatusOK)
+	var task Task
+	decode(t, rec, &task)
+	if len(task.Tags) != 1 || task.Tags[0] != "home" || !task.Completed {
+		t.Errorf("null tags gave %+v, want the tags kept", task)
+	}
+
+	rec = do(app, "PATCH", "/tasks/1", `{"Tags":[]}`)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if len(task.Tags) != 0 {
+		t.Errorf("empty tags gave %q, want them cleared", task.Tags)
+	}
+}
+
+func TestPatchNullTitleIsRejected(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"Title":null}`), http.StatusBadRequest)
+}
+
+func TestPatchNullPriorityResetsToDefault(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants","Priority":"high"}`)
+
+	rec := do(app, "PATCH", "/tasks/1", `{"priority":null}`)
+	expectStatus(t, rec, http.StatusOK)
+	var task Task
+	decode(t, rec, &task)
+	if task.Priority != defaultPriority {
+		t.Errorf("priority is %q after null, want %q", task.Priority, defaultPriority)
+	}
+}
+
+func TestPutLenientResetsMissingFields(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants","Color":"red","Priority":"high","Tags":["home"]}`)
+
+	rec := do(app, "PUT", "/tasks/1", `{"Title":"Water the plants"}`)
+	expectStatus(t, rec, http.StatusOK)
+	var task Task
+	decode(t, rec, &task)
+	if task.Color != "" || task.Priority != defaultPriority || task.Tags != nil {
+		t.Errorf("PUT kept fields it left out: %+v", task)
+	}
+}
+
+func TestPutRequiresTitle(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants","Color":"red"}`)
+
+	rec := do(app, "PUT", "/tasks/1", `{"Completed":true}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	var res errorResponse
+	decode(t, rec, &res)
+	want := "Request body must include a Title to replace a task, use PATCH to change only some fields"
+	if res.Error != want {
+		t.Errorf("got error %q, want %q", res.Error, want)
+	}
+
+	// The same body merges into the task as a PATCH.
+	rec = do(app, "PATCH", "/tasks/1", `{"Completed":true}`)
+	expectStatus(t, rec, http.StatusOK)
+	var task Task
+	decode(t, rec, &task)
+	if task.Title != "Water plants" || task.Color != "red" || !task.Completed {
+		t.Errorf("PATCH stored %+v, want the task completed and otherwise unchanged", task)
+	}
+}
+
+func TestPutStrictRejectsMissingFields(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.putStrict = true
+	createTask(t, app, `{"Title":"Water plants","Color":"red"}`)
+
+	rec := do(app, "PUT", "/tasks/1", `{"Title":"Water the plants","Completed":true}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	var res errorResponse
+	decode(t, rec, &res)
+	want := "Request body is missing Color, Priority, StartDate, Due, Tags, ParentId, PUT must include every writable field"
+	if res.Error != want {
+		t.Errorf("got error %q, want %q", res.Error, want)
+	}
+
+	rec = do(app, "PUT", "/tasks/1", `{"Title":"Water the plants","Completed":true,"Color":"blue","Priority":"low","StartDate":null,"Due":null,"Tags":[],"ParentId":null}`)
+	expectStatus(t, rec, http.StatusOK)
+	var task Task
+	decode(t, rec, &task)
+	if task.Title != "Water the plants" || task.Color != "blue" || !task.Completed {
+		t.Errorf("complete PUT stored %+v", task)
+	}
+}
+
+func TestTrashAndRestoreBumpUpdatedAt(t *testing.T) {
+	app, clock := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+	createTask(t, app, `{"Title":"Feed cat"}`)
+
+	clock.Advance(time.Minute)
+	expectStatus(t, do(app, "DELETE", "/tasks/1", ""), http.StatusNoContent)
+	task, err := app.store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !task.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("trashed task has UpdatedAt %v, want %v", task.UpdatedAt, clock.Now())
+	}
+
+	clock.Advance(time.Minute)
+	rec := do(app, "POST", "/tasks/1/restore", "")
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if !task.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("restored task has UpdatedAt %v, want %v", task.UpdatedAt, clock.Now())
+	}
+
+	rec = do(app, "GET", "/tasks?sort=-updated", "")
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 2 || tasks[0].Id != 1 {
+		t.Errorf("sort=-updated listed %+v, want the restored task first", tasks)
+	}
+}
+
+func TestBatchGet(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants","Color":"red"}`)
+	createTask(t, app, `{"Title":"Feed cat","Priority":"high"}`)
+
+	rec := do(app, "POST", "/tasks/batch-get", `{"ids":[2,99,1],"fields":["id","title"]}`)
+	expectStatus(t, rec, http.StatusOK)
+	var res struct {
+		Tasks   []map[string]any
+		Missing []int
+	}
+	decode(t, rec, &res)
+
+	want := []map[string]any{
+		{"Id": float64(2), "Title": "Feed cat"},
+		{"Id": float64(1), "Title": "Water plants"},
+	}
+	if !reflect.DeepEqual(res.Tasks, want) {
+		t.Errorf("got tasks %v, want %v", res.Tasks, want)
+	}
+	if !reflect.DeepEqual(res.Missing, []int{99}) {
+		t.Errorf("got missing %v, want [99]", res.Missing)
+	}
+
+	// No fields means every field.
+	rec = do(app, "POST", "/tasks/batch-get", `{"ids":[1]}`)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &res)
+	if len(res.Tasks) != 1 || res.Tasks[0]["Color"] != "red" || len(res.Missing) != 0 {
+		t.Errorf("got %+v, want all of task 1", res)
+	}
+
+	expectStatus(t, do(app, "POST", "/tasks/batch-get", `{"ids":[1],"fields":["secret"]}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "GET", "/tasks/batch-get", ""), http.StatusMethodNotAllowed)
+}
+
+func TestIdempotentDelete(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+
+	expectStatus(t, do(app, "DELETE", "/tasks/1?idempotent=true", ""), http.StatusNoContent)
+	expectStatus(t, do(app, "DELETE", "/tasks/99?idempotent=true", ""), http.StatusNoContent)
+	expectStatus(t, do(app, "DELETE", "/tasks/99", ""), http.StatusNotFound)
+
+	// Genuine errors are still errors.
+	app.store = brokenStore{TaskStore: app.store, getErr: os.ErrPermission}
+	expectStatus(t, do(app, "DELETE", "/tasks/1?idempotent=true", ""), http.StatusInternalServerError)
+}
+
+func TestWelcome(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	rec := do(app, "GET", "/", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "Welcome to Brain!" {
+		t.Errorf("got %q, want the welcome text", rec.Body.String())
+	}
+	expectStatus(t, do(app, "GET", "/nowhere", ""), http.StatusNotFound)
+
+	app.rootRedirect = "/tasks"
+	rec = do(app, "GET", "/", "")
+	expectStatus(t, rec, http.StatusFound)
+	if location := rec.Header().Get("Location"); location != "/tasks" {
+		t.Errorf("redirected to %q, want /tasks", location)
+	}
+}
+
+func TestListEmpty(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	rec := do(app, "GET", "/tasks", "")
+	expectStatus(t, rec, http.StatusOK)
+	if body := rec.Body.String(); body != "[]" {
+		t.Errorf("got body %q, want []", body)
+	}
+	if total := rec.Header().Get("X-Total-Count"); total != "0" {
+		t.Errorf("got X-Total-Count %q, want 0", total)
+	}
+}
+
+func TestIds(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Buy milk"}`)
+	createTask(t, app, `{"Title":"Fix sink","Completed":true}`)
+	createTask(t, app, `{"Title":"Buy eggs"}`)
+	createTask(t, app, `{"Title":"Buy bread"}`)
+	expectStatus(t, do(app, "DELETE", "/tasks/4", ""), http.StatusNoContent)
+
+	tests := []struct {
+		target string
+		want   []int
+	}{
+		{"/tasks/ids", []int{1, 2, 3}},
+		{"/tasks/ids?q=buy", []int{1, 3}},
+		{"/tasks/ids?completed=true", []int{2}},
+		{"/tasks/ids?include_deleted=true", []int{1, 2, 3, 4}},
+		{"/tasks/ids?q=nothing", []int{}},
+	}
+	for _, test := range tests {
+		rec := do(app, "GET", test.target, "")
+		expectStatus(t, rec, http.StatusOK)
+		var ids []int
+		decode(t, rec, &ids)
+		if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("%s: got %v, want %v", test.target, ids, test.want)
+		}
+	}
+}
+
+func TestLockCompleted(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.lockCompleted = true
+	createTask(t, app, `{"Title":"Water plants","Completed":true}`)
+	createTask(t, app, `{"Title":"Feed cat"}`)
+
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"Title":"Water the plants"}`), http.StatusConflict)
+	expectStatus(t, do(app, "PUT", "/tasks/1", `{"Title":"Water the plants","Completed":true}`), http.StatusConflict)
+	expectStatus(t, do(app, "DELETE", "/tasks/1/due", ""), http.StatusOK)
+
+	// Sending what's already there changes nothing, so it's allowed.
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"Completed":true}`), http.StatusOK)
+
+	rec := do(app, "PATCH", "/tasks/1", `{"Completed":false}`)
+	expectStatus(t, rec, http.StatusOK)
+	var task Task
+	decode(t, rec, &task)
+	if task.Completed {
+		t.Error("un-completing a locked task didn't")
+	}
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"Title":"Water the plants"}`), http.StatusOK)
+
+	// Incomplete tasks were never locked.
+	expectStatus(t, do(app, "PATCH", "/tasks/2", `{"Title":"Feed the cat"}`), http.StatusOK)
+}
+
+func TestReturnChanges(t *testing.T) {
+	app, clock := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants","Tags":["home"]}`)
+	clock.Advance(time.Minute)
+
+	rec := do(app, "PATCH", "/tasks/1?return=changes", `{"Completed":true,"Tags":["home"]}`)
+	expectStatus(t, rec, http.StatusOK)
+	var changes map[string]json.RawMessage
+	decode(t, rec, &changes)
+	want := map[string]json.RawMessage{
+		"Id":        json.RawMessage(`1`),
+		"Completed": json.RawMessage(`true`),
+		"UpdatedAt": json.RawMessage(`"2024-03-04T09:31:00Z"`),
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("got changes %s, want Id, Completed and UpdatedAt", rec.Body.String())
+	}
+
+	// Without it, the whole task comes back as before.
+	rec = do(app, "PATCH", "/tasks/1", `{"Completed":false}`)
+	expectStatus(t, rec, http.StatusOK)
+	var task Task
+	decode(t, rec, &task)
+	if task.Title != "Water plants" || task.Completed {
+		t.Errorf("got %+v, want the whole task", task)
+	}
+}
+
+func TestHandlersStopAfterErrors(t *testing.T) {
+	app, _ := newTestApp(t)
+	store := app.store
+
+	tests := []struct {
+		method, target, body string
+		status               int
+	}{
+		{"GET", "/tasks/one", "", http.StatusBadRequest},
+		{"PATCH", "/tasks/one", `{"Title":"Feed cat"}`, http.StatusBadRequest},
+		{"GET", "/tasks/7", "", http.StatusNotFound},
+		{"PATCH", "/tasks/7", `{"Title":"Feed cat"}`, http.StatusNotFound},
+		{"PUT", "/tasks/7", `{"Title":"Feed cat"}`, http.StatusNotFound},
+		{"GET", "/tasks", "", http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		app.store = store
+		if test.status == http.StatusInternalServerError {
+			app.store = brokenStore{TaskStore: store, listErr: errors.New("disk on fire")}
+		}
+
+		// A handler that carried on would append a second response to the
+		// body, which then no longer decodes as one error.
+		rec := do(app, test.method, test.target, test.body)
+		if rec.Code != test.status {
+			t.Errorf("%s %s: got status %d, want %d", test.method, test.target, rec.Code, test.status)
+			continue
+		}
+		var res errorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil || res.Error == "" {
+			t.Errorf("%s %s: got body %q, want a single JSON error", test.method, test.target, rec.Body.String())
+		}
+	}
+
+	tasks, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("failed requests stored %+v", tasks)
+	}
+}
+
+func TestCreateStatus(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	// A single task is a new resource of its own.
+	rec := do(app, "POST", "/tasks", `{"Title":"Water plants"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	location := rec.Header().Get("Location")
+	if location != "/tasks/1" {
+		t.Errorf("got Location %q, want /tasks/1", location)
+	}
+	var task Task
+	decode(t, do(app, "GET", location, ""), &task)
+	if task.Title != "Water plants" {
+		t.Errorf("Location serves %+v, want the created task", task)
+	}
+
+	// Batches have no single resource to point at, so they get a summary.
+	for target, body := range map[string]string{
+		"/tasks":                 `[{"Title":"Feed cat"},{"Title":"Walk dog"}]`,
+		"/tasks/import/markdown": "- [ ] Pack\n- [ ] Go\n",
+	} {
+		rec = do(app, "POST", target, body)
+		expectStatus(t, rec, http.StatusOK)
+		if location := rec.Header().Get("Location"); location != "" {
+			t.Errorf("POST %s: got Location %q, want none", target, location)
+		}
+	}
+}
+
+func TestConcurrentCreatesGetDistinctIds(t *testing.T) {
+	app, _ := newTestApp(t)
+	handler := app.routes()
+
+	const count = 50
+	ids := make(chan int, count)
+	var wg sync.WaitGroup
+	for index := 0; index < count; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, newRequest("POST", "/tasks", `{"Title":"Water plants"}`))
+			var task Task
+			if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &task) != nil {
+				t.Errorf("got status %d, body %s", rec.Code, rec.Body.String())
+				return
+			}
+			ids <- task.Id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[int]bool{}
+	for taskId := range ids {
+		if seen[taskId] {
+			t.Errorf("task %d was created more than once", taskId)
+		}
+		seen[taskId] = true
+	}
+	tasks, err := app.store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != count || len(tasks) != count {
+		t.Errorf("got %d IDs and %d stored tasks, want %d of each", len(seen), len(tasks), count)
+	}
+}
+
+func TestWritesRespondWithJSON(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	for _, test := range []struct {
+		method, target, body string
+		status               int
+		location             string
+		title                string
+	}{
+		{"POST", "/tasks", `{"Title":"Water plants"}`, http.StatusCreated, "/tasks/1", "Water plants"},
+		{"PATCH", "/tasks/1", `{"Title":"Water the plants"}`, http.StatusOK, "", "Water the plants"},
+		{"PUT", "/tasks/1", `{"Title":"Feed cat"}`, http.StatusOK, "", "Feed cat"},
+	} {
+		rec := do(app, test.method, test.target, test.body)
+		expectStatus(t, rec, test.status)
+		if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("%s %s: got Content-Type %q", test.method, test.target, contentType)
+		}
+		if location := rec.Header().Get("Location"); location != test.location {
+			t.Errorf("%s %s: got Location %q, want %q", test.method, test.target, location, test.location)
+		}
+		var task Task
+		err := json.Unmarshal(rec.Body.Bytes(), &task)
+		if err != nil {
+			t.Fatalf("%s %s: body %q isn't a task: %v", test.method, test.target, rec.Body.String(), err)
+		}
+		if task.Id != 1 || task.Title != test.title {
+			t.Errorf("%s %s: got %+v, want task 1 titled %q", test.method, test.target, task, test.title)
+		}
+
+		// What the response says is what was stored.
+		stored, err := app.store.Get(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(stored, task) {
+			t.Errorf("%s %s: responded with %+v, stored %+v", test.method, test.target, task, stored)
+		}
+	}
+}
+
+func TestUpdateReportsWriteErrors(t *testing.T) {
+	app, _ := newTestApp(t)
+	dir := t.TempDir()
+	flaky := &flakyFS{}
+	var err error
+	app.store, err = newFileStore(dir, flaky, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTask(t, app, `{"Title":"Water plants"}`)
+
+	flaky.writeErrs = []error{errors.New("read-only file system")}
+	rec := do(app, "PATCH", "/tasks/1", `{"Title":"Feed cat"}`)
+	expectStatus(t, rec, http.StatusInternalServerError)
+	var res errorResponse
+	decode(t, rec, &res)
+	if !strings.Contains(res.Error, "read-only file system") {
+		t.Errorf("got error %q, want the write error", res.Error)
+	}
+
+	// Neither the index nor the file on disk took the change.
+	var task Task
+	decode(t, do(app, "GET", "/tasks/1", ""), &task)
+	if task.Title != "Water plants" {
+		t.Errorf("served %q after a failed update", task.Title)
+	}
+	store, err := newFileStore(dir, osFS{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task, err = store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Title != "Water plants" {
+		t.Errorf("stored %q after a failed update", task.Title)
+	}
+}
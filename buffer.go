@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type pendingWrite struct {
+	data []byte
+	perm os.FileMode
+}
+
+// bufferedFS holds rewrites of existing files in memory and writes them out
+// on Flush, so rapid updates to the same task cost one disk write. New
+// files are written through immediately so that directory scans (and ID
+// allocation) always see them, and reads see pending data.
+//
+// The tradeoff is durability: updates accepted since the last flush are
+// lost if the process dies without flushing.
+type bufferedFS struct {
+	fs taskFS
+
+	mu      sync.Mutex
+	pending map[string]pendingWrite
+}
+
+func newBufferedFS(fs taskFS) *bufferedFS {
+	return &bufferedFS{fs: fs, pending: make(map[string]pendingWrite)}
+}
+
+func (bfs *bufferedFS) ReadFile(name string) ([]byte, error) {
+	bfs.mu.Lock()
+	defer bfs.mu.Unlock()
+
+	if write, ok := bfs.pending[name]; ok {
+		return append([]byte(nil), write.data...), nil
+	}
+	return bfs.fs.ReadFile(name)
+}
+
+func (bfs *bufferedFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	bfs.mu.Lock()
+	defer bfs.mu.Unlock()
+
+	_, buffered := bfs.pending[name]
+	if !buffered {
+		_, err := os.Stat(name)
+		if errors.Is(err, os.ErrNotExist) {
+			return bfs.fs.WriteFile(name, data, perm)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	bfs.pending[name] = pendingWrite{data: append([]byte(nil), data...), perm: perm}
+	return nil
+}
+
+func (bfs *bufferedFS) Remove(name string) error {
+	bfs.mu.Lock()
+	defer bfs.mu.Unlock()
+
+	delete(bfs.pending, name)
+	return bfs.fs.Remove(name)
+}
+
+// Flush writes every pending file to disk. Files that fail to write stay
+// pending for the next flush.
+func (bfs *bufferedFS) Flush() error {
+	bfs.mu.Lock()
+	defer bfs.mu.Unlock()
+
+	var errs []error
+	for name, write := range bfs.pending {
+		err := bfs.fs.WriteFile(name, write.data, write.perm)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(bfs.pending, name)
+	}
+	return errors.Join(errs...)
+}
+
+// flushEvery flushes pending writes at each interval, forever.
+func (bfs *bufferedFS) flushEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		err := bfs.Flush()
+		if err != nil {
+			log.Printf("flushing buffered writes: %s", err.Error())
+		}
+	}
+}
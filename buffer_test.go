@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBufferedFSCoalescesRewrites(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "1.json")
+	flaky := &flakyFS{}
+	bfs := newBufferedFS(flaky)
+
+	// New files go straight to disk.
+	err := bfs.WriteFile(name, []byte("v1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flaky.writes != 1 {
+		t.Fatalf("wrote %d times creating the file, want 1", flaky.writes)
+	}
+
+	for _, data := range []string{"v2", "v3", "v4"} {
+		err = bfs.WriteFile(name, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if flaky.writes != 1 {
+		t.Errorf("wrote %d times before flushing, want 1", flaky.writes)
+	}
+	expectFile(t, name, "v1")
+	data, err := bfs.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "v4" {
+		t.Errorf("read %q before flushing, want the pending v4", data)
+	}
+
+	err = bfs.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flaky.writes != 2 {
+		t.Errorf("wrote %d times after flushing, want 2", flaky.writes)
+	}
+	expectFile(t, name, "v4")
+
+	err = bfs.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flaky.writes != 2 {
+		t.Error("flushing with nothing pending wrote again")
+	}
+}
+
+func TestBufferedFSKeepsFailedFlushes(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "1.json")
+	flaky := &flakyFS{}
+	bfs := newBufferedFS(flaky)
+	err := bfs.WriteFile(name, []byte("v1"), 0644)
+	if err == nil {
+		err = bfs.WriteFile(name, []byte("v2"), 0644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diskFull := errors.New("no space left on device")
+	flaky.writeErrs = []error{diskFull}
+	err = bfs.Flush()
+	if !errors.Is(err, diskFull) {
+		t.Fatalf("flush returned %v, want %v", err, diskFull)
+	}
+	expectFile(t, name, "v1")
+
+	err = bfs.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, name, "v2")
+}
+
+func TestBufferedFSRemoveDropsPending(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "1.json")
+	bfs := newBufferedFS(osFS{})
+	err := bfs.WriteFile(name, []byte("v1"), 0644)
+	if err == nil {
+		err = bfs.WriteFile(name, []byte("v2"), 0644)
+	}
+	if err == nil {
+		err = bfs.Remove(name)
+	}
+	if err == nil {
+		err = bfs.Flush()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Stat(name)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("flush recreated a removed file: %v", err)
+	}
+}
+
+// expectFile fails the test unless the file at name holds contents.
+func expectFile(t *testing.T, name, contents string) {
+	t.Helper()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != contents {
+		t.Errorf("%s holds %q, want %q", filepath.Base(name), data, contents)
+	}
+}
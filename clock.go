@@ -0,0 +1,15 @@
+package main
+
+import "time"
+
+// Clock is the source of "now" for anything time-based, so that behavior
+// like expiry or overdue checks can be driven deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// colorPalette lists the named colors a task may be labelled with. Any
+// #rgb or #rrggbb hex code is accepted as well.
+var colorPalette = []string{"red", "orange", "yellow", "green", "blue", "purple", "pink", "gray"}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// normalizeColor lowercases a color label and checks it is either a
+// palette name or a hex code. The empty string means no color.
+func normalizeColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" || slices.Contains(colorPalette, color) || hexColor.MatchString(color) {
+		return color, nil
+	}
+
+	msg := fmt.Sprintf("Invalid color %q, must be one of %s or a hex code like #ff8800", color, strings.Join(colorPalette, ", "))
+	return "", &malformedRequest{status: http.StatusBadRequest, msg: msg}
+}
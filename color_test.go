@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeColor(t *testing.T) {
+	for input, want := range map[string]string{
+		"":          "",
+		"red":       "red",
+		" Blue ":    "blue",
+		"#FF8800":   "#ff8800",
+		"#abc":      "#abc",
+		"GRAY":      "gray",
+		"  #0a0B0c": "#0a0b0c",
+	} {
+		got, err := normalizeColor(input)
+		if err != nil || got != want {
+			t.Errorf("normalizeColor(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"crimson", "#ff88", "ff8800", "#gggggg"} {
+		_, err := normalizeColor(input)
+		if err == nil {
+			t.Errorf("normalizeColor(%q) accepted an invalid color", input)
+		}
+	}
+}
+
+func TestTaskColors(t *testing.T) {
+	app, _ := newTestApp(t)
+	task := createTask(t, app, `{"Title":"Buy milk","Color":"Red"}`)
+	if task.Color != "red" {
+		t.Errorf("created with color %q, want red", task.Color)
+	}
+	createTask(t, app, `{"Title":"Buy eggs","Color":"#00FF00"}`)
+	createTask(t, app, `{"Title":"Buy bread"}`)
+
+	rec := do(app, "PATCH", "/tasks/3", `{"Color":"red"}`)
+	expectStatus(t, rec, http.StatusOK)
+
+	if got, want := listIds(t, app, "/tasks?color=RED"), []int{1, 3}; !slices.Equal(got, want) {
+		t.Errorf("color=RED listed %v, want %v", got, want)
+	}
+	if got, want := listIds(t, app, "/tasks?color=%2300ff00"), []int{2}; !slices.Equal(got, want) {
+		t.Errorf("color=#00ff00 listed %v, want %v", got, want)
+	}
+
+	expectStatus(t, do(app, "POST", "/tasks", `{"Title":"Buy jam","Color":"crimson"}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"Color":"#12"}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "GET", "/tasks?color=crimson", ""), http.StatusBadRequest)
+}
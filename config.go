@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// serverSettings records configuration main reads from the environment
+// but that no handler needs, so GET /admin/config can report it.
+type serverSettings struct {
+	port           string
+	tlsEnabled     bool
+	tlsCert        string
+	tlsKey         string
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	idleTimeout    time.Duration
+	fsRetries      int
+	fsRetryBackoff time.Duration
+	writeBuffer    time.Duration
+	backupOnStart  bool
+	backupDir      string
+	backupKeep     int
+	otelEndpoint   string
+	storeType      string
+	tasksDir       string
+	storePretty    bool
+	sqlitePath     string
+}
+
+type limitConfig struct {
+	Soft int `json:"soft"`
+	Hard int `json:"hard"`
+}
+
+type configResponse struct {
+	Server struct {
+		Port            string `json:"port"`
+		TLSEnabled      bool   `json:"tlsEnabled"`
+		TLSCert         string `json:"tlsCert,omitempty"`
+		TLSKey          string `json:"tlsKey,omitempty"`
+		ReadTimeout     string `json:"readTimeout"`
+		WriteTimeout    string `json:"writeTimeout"`
+		IdleTimeout     string `json:"idleTimeout"`
+		ShutdownTimeout string `json:"shutdownTimeout"`
+	} `json:"server"`
+	Auth struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auth"`
+	Store struct {
+		Type          string `json:"type"`
+		FallbackError string `json:"fallbackError,omitempty"`
+		TasksDir      string `json:"tasksDir,omitempty"`
+		Pretty        bool   `json:"pretty"`
+		SQLitePath    string `json:"sqlitePath,omitempty"`
+		Retries       int    `json:"retries"`
+		RetryBackoff  string `json:"retryBackoff"`
+		WriteBuffer   string `json:"writeBuffer"`
+		FocusPath     string `json:"focusPath"`
+		BackupOnStart bool   `json:"backupOnStart"`
+		BackupDir     string `json:"backupDir,omitempty"`
+		BackupKeep    int    `json:"backupKeep,omitempty"`
+	} `json:"store"`
+	Limits struct {
+		Tasks          limitConfig `json:"tasks"`
+		Tags           limitConfig `json:"tags"`
+		TitleMaxLength int         `json:"titleMaxLength"`
+		TitleMaxWords  int         `json:"titleMaxWords"`
+		SubtaskDepth   int         `json:"subtaskDepth"`
+		IPWarnRequests int         `json:"ipWarnRequests"`
+		IPWarnWindow   string      `json:"ipWarnWindow"`
+		RequestRate    float64     `json:"requestRate"`
+		RequestBurst   int         `json:"requestBurst"`
+	} `json:"limits"`
+	Features struct {
+		RootRedirect    string            `json:"rootRedirect"`
+		StaticPath      string            `json:"staticPath"`
+		LockCompleted   bool              `json:"lockCompleted"`
+		PutStrict       bool              `json:"putStrict"`
+		HideSubtasks    bool              `json:"hideSubtasks"`
+		SafeDelete      bool              `json:"safeDelete"`
+		NormalizeTitle  bool              `json:"normalizeTitle"`
+		CapitalizeTitle bool              `json:"capitalizeTitle"`
+		SecurityHeaders map[string]string `json:"securityHeaders"`
+		SessionTTL      string            `json:"sessionTTL"`
+		TracingEnabled  bool              `json:"tracingEnabled"`
+		TracingEndpoint string            `json:"tracingEndpoint,omitempty"`
+		LogSample       float64           `json:"logSample"`
+	} `json:"features"`
+}
+
+// redacted stands in for secrets in GET /admin/config.
+const redacted = "[redacted]"
+
+// adminConfig reports the effective configuration so operators can check
+// what is active. Credentials are never included.
+func (app *application) adminConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		msg := fmt.Sprintf("Unsupported request method %v to /admin/config", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	var res configResponse
+	res.Server.Port = app.settings.port
+	res.Server.TLSEnabled = app.settings.tlsEnabled
+	if app.settings.tlsEnabled {
+		res.Server.TLSCert = app.settings.tlsCert
+		res.Server.TLSKey = app.settings.tlsKey
+	}
+	res.Server.ReadTimeout = app.settings.readTimeout.String()
+	res.Server.WriteTimeout = app.settings.writeTimeout.String()
+	res.Server.IdleTimeout = app.settings.idleTimeout.String()
+	res.Server.ShutdownTimeout = shutdownTimeout.String()
+
+	res.Auth.Username = redacted
+	res.Auth.Password = redacted
+
+	res.Store.Type = app.settings.storeType
+	if app.storeFallback != nil {
+		res.Store.FallbackError = app.storeFallback.Error()
+	}
+	res.Store.TasksDir = app.settings.tasksDir
+	res.Store.Pretty = app.settings.storePretty
+	res.Store.SQLitePath = app.settings.sqlitePath
+	res.Store.Retries = app.settings.fsRetries
+	res.Store.RetryBackoff = app.settings.fsRetryBackoff.String()
+	res.Store.WriteBuffer = app.settings.writeBuffer.String()
+	res.Store.FocusPath = app.focusLists.path
+	res.Store.BackupOnStart = app.settings.backupOnStart
+	if app.settings.backupOnStart {
+		res.Store.BackupDir = app.settings.backupDir
+		res.Store.BackupKeep = app.settings.backupKeep
+	}
+
+	res.Limits.Tasks = limitConfig{Soft: app.taskLimit.soft, Hard: app.taskLimit.hard}
+	res.Limits.Tags = limitConfig{Soft: app.tagLimit.soft, Hard: app.tagLimit.hard}
+	res.Limits.TitleMaxLength = app.titleMaxLength
+	res.Limits.TitleMaxWords = app.titleMaxWords
+	res.Limits.SubtaskDepth = app.maxSubtaskDepth
+	res.Limits.IPWarnRequests = app.rateWatcher.threshold
+	res.Limits.IPWarnWindow = app.rateWatcher.window.String()
+	if app.rateLimiter != nil {
+		res.Limits.RequestRate = float64(app.rateLimiter.limit)
+		res.Limits.RequestBurst = app.rateLimiter.burst
+	}
+
+	res.Features.RootRedirect = app.rootRedirect
+	res.Features.StaticPath = app.staticPath
+	res.Features.LockCompleted = app.lockCompleted
+	res.Features.PutStrict = app.putStrict
+	res.Features.HideSubtasks = app.hideSubtasks
+	res.Features.SafeDelete = app.safeDelete
+	res.Features.NormalizeTitle = app.normalizeTitle
+	res.Features.CapitalizeTitle = app.capitalizeTitle
+	res.Features.SecurityHeaders = app.headers
+	res.Features.SessionTTL = app.sessions.ttl.String()
+	res.Features.TracingEnabled = app.settings.otelEndpoint != ""
+	res.Features.TracingEndpoint = redactURL(app.settings.otelEndpoint)
+	res.Features.LogSample = app.logSample
+
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while reading the configuration, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resJson)
+}
+
+// redactURL masks any password embedded in a URL, such as a collector
+// endpoint with credentials in its userinfo.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
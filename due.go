@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// clearDue unschedules a task by removing its due date, and responds with
+// the task. PATCH {"Due":null} does the same.
+func (app *application) clearDue(w http.ResponseWriter, r *http.Request, taskId int) {
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
+
+	task, err := app.readTask(taskId)
+	if errors.Is(err, os.ErrNotExist) {
+		msg := fmt.Sprintf("Task with ID %v not found", taskId)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving your task, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	if task.Due != nil {
+		if app.lockCompleted && task.Completed {
+			msg := fmt.Sprintf("Task with ID %v is completed and locked, un-complete it before editing", taskId)
+			writeJSONError(w, http.StatusConflict, msg)
+			return
+		}
+
+		task.Due = nil
+		task.UpdatedAt = app.clock.Now()
+		err = app.store.Update(task)
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		app.sessions.touch(sessionId(r), task.Id, app.clock.Now())
+	}
+
+	taskJson, err := json.Marshal(task)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	w.Header().Set("ETag", taskETag(taskJson, formatJSON))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(taskJson)
+}
+
+// dueBuckets are the counts GET /tasks/buckets reports, for planning views.
+type dueBuckets struct {
+	Overdue   int `json:"overdue"`
+	Today     int `json:"today"`
+	ThisWeek  int `json:"this_week"`
+	Later     int `json:"later"`
+	NoDueDate int `json:"no_due_date"`
+}
+
+// dueBuckets counts matching tasks by when they are due, relative to now
+// in the timezone given by ?tz=, or the server's. Weeks end on Sunday.
+// Completed tasks are left out unless ?completed= says otherwise.
+func (app *application) dueBuckets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/buckets", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	query, err := app.parseTaskQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.completed == nil {
+		completed := false
+		query.completed = &completed
+	}
+
+	location := app.location
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		location, err = time.LoadLocation(tz)
+		if err != nil {
+			msg := fmt.Sprintf("Invalid tz %q, expected an IANA timezone like Europe/Berlin", tz)
+			writeJSONError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
+	tasks, err := app.store.List()
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	now := app.clock.Now().In(location)
+	today := startOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	// Weekday counts from Sunday, so shift it to count days since Monday.
+	nextWeek := today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+
+	var buckets dueBuckets
+	for _, task := range tasks {
+		if !query.matches(task) {
+			continue
+		}
+		switch {
+		case task.Due == nil:
+			buckets.NoDueDate++
+		case task.Due.Before(now):
+			buckets.Overdue++
+		case task.Due.Before(tomorrow):
+			buckets.Today++
+		case task.Due.Before(nextWeek):
+			buckets.ThisWeek++
+		default:
+			buckets.Later++
+		}
+	}
+
+	bucketsJson, err := json.Marshal(buckets)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bucketsJson)
+}
+
+// startOfDay returns midnight at the start of t's day in t's location.
+// Going through time.Date rather than truncating keeps it right on days
+// that a DST change makes 23 or 25 hours long.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClearDue(t *testing.T) {
+	app, clock := newTestApp(t)
+	before := createTask(t, app, `{"Title":"File taxes","Due":"2024-04-15T17:00:00Z","Priority":"high","Tags":["admin"]}`)
+	clock.Advance(time.Minute)
+
+	rec := do(app, "DELETE", "/tasks/1/due", "")
+	expectStatus(t, rec, http.StatusOK)
+	var after Task
+	decode(t, rec, &after)
+	if after.Due != nil {
+		t.Errorf("due date is still %v", after.Due)
+	}
+
+	// Nothing else changes, apart from UpdatedAt.
+	before.Due = nil
+	before.UpdatedAt = clock.Now()
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("got %+v, want %+v", after, before)
+	}
+
+	stored, err := app.store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Due != nil {
+		t.Errorf("stored due date is still %v", stored.Due)
+	}
+
+	expectStatus(t, do(app, "DELETE", "/tasks/2/due", ""), http.StatusNotFound)
+	expectStatus(t, do(app, "POST", "/tasks/1/due", ""), http.StatusMethodNotAllowed)
+}
+
+func TestDueBuckets(t *testing.T) {
+	app, _ := newTestApp(t)
+	// testNow is Monday 09:30 UTC.
+	for _, body := range []string{
+		`{"Title":"Renew passport","Due":"2024-03-01T12:00:00Z"}`,
+		`{"Title":"Call plumber","Due":"2024-03-04T08:00:00Z"}`,
+		`{"Title":"Buy milk","Due":"2024-03-04T18:00:00Z"}`,
+		`{"Title":"Water plants","Due":"2024-03-05T05:00:00Z"}`,
+		`{"Title":"Mow lawn","Due":"2024-03-10T23:59:00Z"}`,
+		`{"Title":"File taxes","Due":"2024-03-11T00:00:00Z"}`,
+		`{"Title":"Read a book"}`,
+		`{"Title":"Pay rent","Due":"2024-03-04T18:00:00Z","Completed":true}`,
+		`{"Title":"Cancel gym","Due":"2024-03-04T18:00:00Z"}`,
+	} {
+		createTask(t, app, body)
+	}
+	expectStatus(t, do(app, "DELETE", "/tasks/9", ""), http.StatusNoContent)
+
+	tests := []struct {
+		target string
+		want   dueBuckets
+	}{
+		{"/tasks/buckets", dueBuckets{Overdue: 2, Today: 1, ThisWeek: 2, Later: 1, NoDueDate: 1}},
+		{"/tasks/buckets?completed=true", dueBuckets{Today: 1}},
+		// 01:30 on Monday in Los Angeles, where 05:00 UTC Tuesday is still
+		// Monday evening and the week runs until 07:00 UTC next Monday.
+		{"/tasks/buckets?tz=America/Los_Angeles", dueBuckets{Overdue: 2, Today: 2, ThisWeek: 2, NoDueDate: 1}},
+	}
+	for _, test := range tests {
+		rec := do(app, "GET", test.target, "")
+		expectStatus(t, rec, http.StatusOK)
+		var got dueBuckets
+		decode(t, rec, &got)
+		if got != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.target, got, test.want)
+		}
+	}
+
+	expectStatus(t, do(app, "GET", "/tasks/buckets?tz=Mars/Olympus", ""), http.StatusBadRequest)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	inPattern    = regexp.MustCompile(`^in (\d+) (minute|hour|day|week)s?\b`)
+	clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))? ?(am|pm)?$`)
+)
+
+// parseDueText reads a due date written the way people say it, relative
+// to now and in now's timezone:
+//
+//	today, tomorrow, monday, next friday, 2024-03-10, in 3 days
+//
+// optionally followed by a time like 5pm, 9:30am, 17:00 or noon, or a time
+// alone for today. A day without a time is due at the end of that day. A
+// weekday is the next one after today. Errors quote whatever wasn't
+// understood.
+func parseDueText(text string, now time.Time) (time.Time, error) {
+	rest := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	if rest == "" {
+		return time.Time{}, &malformedRequest{status: http.StatusBadRequest, msg: "due_text must not be empty"}
+	}
+
+	if match := inPattern.FindStringSubmatch(rest); match != nil {
+		count, _ := strconv.Atoi(match[1])
+		rest = strings.TrimSpace(rest[len(match[0]):])
+		if rest != "" {
+			return time.Time{}, dueTextError(text, rest)
+		}
+		switch match[2] {
+		case "minute":
+			return now.Add(time.Duration(count) * time.Minute), nil
+		case "hour":
+			return now.Add(time.Duration(count) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, count), nil
+		default:
+			return now.AddDate(0, 0, 7*count), nil
+		}
+	}
+
+	day := startOfDay(now)
+	dayWord, afterDay, _ := strings.Cut(rest, " ")
+	if dayWord == "next" {
+		dayWord, afterDay, _ = strings.Cut(afterDay, " ")
+		if _, ok := weekdays[dayWord]; !ok {
+			return time.Time{}, dueTextError(text, rest)
+		}
+	}
+	datePart := true
+	switch weekday, isWeekday := weekdays[dayWord]; {
+	case dayWord == "today":
+	case dayWord == "tomorrow":
+		day = day.AddDate(0, 0, 1)
+	case isWeekday:
+		days := (int(weekday)-int(day.Weekday())+6)%7 + 1
+		day = day.AddDate(0, 0, days)
+	default:
+		date, err := time.ParseInLocation(time.DateOnly, dayWord, now.Location())
+		if err == nil {
+			day = date
+		} else {
+			datePart = false
+		}
+	}
+	if datePart {
+		rest = afterDay
+	}
+	if rest == "" {
+		return day.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+
+	hour, minute, ok := parseClock(rest)
+	if !ok {
+		return time.Time{}, dueTextError(text, rest)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location()), nil
+}
+
+// applyDueText sets task's due date from a due_text, if one was given.
+func (app *application) applyDueText(task Task, dueText *string) (Task, error) {
+	if dueText == nil {
+		return task, nil
+	}
+	if task.Due != nil {
+		return task, &malformedRequest{status: http.StatusBadRequest, msg: "Request body must not include both Due and due_text"}
+	}
+
+	due, err := parseDueText(*dueText, app.clock.Now().In(app.location))
+	if err != nil {
+		return task, err
+	}
+	task.Due = &due
+	return task, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseClock reads a time of day like 5pm, 9:30 am, 17:00 or noon.
+func parseClock(text string) (hour, minute int, ok bool) {
+	text = strings.TrimPrefix(text, "at ")
+	switch text {
+	case "noon":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+
+	match := clockPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0, 0, false
+	}
+	hour, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	switch match[3] {
+	case "":
+		// A bare number like "5" is more likely a typo than a time.
+		if match[2] == "" {
+			return 0, 0, false
+		}
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if match[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+func dueTextError(text, unparsed string) error {
+	msg := fmt.Sprintf("Could not understand %q in due_text %q", unparsed, text)
+	return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+}
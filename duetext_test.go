@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDueText(t *testing.T) {
+	// testNow is Monday 4 March 2024, 09:30 UTC.
+	at := func(day, hour, minute, second int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, second, 0, time.UTC)
+	}
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		{"today", at(4, 23, 59, 59)},
+		{"tomorrow 5pm", at(5, 17, 0, 0)},
+		{"Tomorrow at 9:30 AM", at(5, 9, 30, 0)},
+		{"next monday", at(11, 23, 59, 59)},
+		{"monday noon", at(11, 12, 0, 0)},
+		{"friday 17:00", at(8, 17, 0, 0)},
+		{"sunday 12am", at(10, 0, 0, 0)},
+		{"5pm", at(4, 17, 0, 0)},
+		{"2024-03-20 8am", at(20, 8, 0, 0)},
+		{"in 3 days", at(7, 9, 30, 0)},
+		{"in 2 hours", at(4, 11, 30, 0)},
+		{"in 1 week", at(11, 9, 30, 0)},
+	}
+	for _, test := range tests {
+		got, err := parseDueText(test.text, testNow)
+		if err != nil {
+			t.Errorf("%q: %v", test.text, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%q: got %v, want %v", test.text, got, test.want)
+		}
+	}
+
+	for _, text := range []string{"", "someday", "tomorrow blorp", "next week", "13pm", "in 3 days 5pm", "5"} {
+		_, err := parseDueText(text, testNow)
+		if err == nil {
+			t.Errorf("%q: parsed, want an error", text)
+		}
+	}
+}
+
+func TestDueTextOnCreateAndUpdate(t *testing.T) {
+	app, clock := newTestApp(t)
+
+	task := createTask(t, app, `{"Title":"Buy milk","due_text":"tomorrow 5pm"}`)
+	if want := time.Date(2024, time.March, 5, 17, 0, 0, 0, time.UTC); task.Due == nil || !task.Due.Equal(want) {
+		t.Errorf("created due %v, want %v", task.Due, want)
+	}
+
+	clock.Advance(48 * time.Hour)
+	rec := do(app, "PATCH", "/tasks/1", `{"due_text":"next monday 9am"}`)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if want := time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC); task.Due == nil || !task.Due.Equal(want) {
+		t.Errorf("updated due %v, want %v", task.Due, want)
+	}
+
+	rec = do(app, "POST", "/tasks", `{"Title":"Buy eggs","due_text":"tomorrow blorp"}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	if !strings.Contains(rec.Body.String(), "blorp") {
+		t.Errorf("error %s doesn't quote the unparsed text", rec.Body.String())
+	}
+	expectStatus(t, do(app, "POST", "/tasks", `[{"Title":"Buy eggs","due_text":"soon"}]`), http.StatusBadRequest)
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"due_text":"tomorrow","Due":null}`), http.StatusBadRequest)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+// collectionETag derives a strong ETag for a list response from its
+// rendered body and total count.
+func collectionETag(body []byte, total string) string {
+	hash := sha256.New()
+	hash.Write(body)
+	hash.Write([]byte{0})
+	hash.Write([]byte(total))
+	return hashETag(hash)
+}
+
+// hashETag makes a strong ETag from a hash of everything that identifies
+// a response.
+func hashETag(hash hash.Hash) string {
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// taskETag derives a strong ETag for a task shown in format from its JSON
+// encoding, so it changes whenever any field does. Strong validators must
+// differ between representations, so each format gets its own.
+func taskETag(taskJson []byte, format responseFormat) string {
+	hash := sha256.New()
+	hash.Write([]byte(format.name))
+	hash.Write([]byte{0})
+	hash.Write(taskJson)
+	return hashETag(hash)
+}
+
+// taskETagMatches reports whether an If-Match header lists the ETag of
+// the task in any format, which lets PUT and PATCH use an ETag from any
+// GET /tasks/{id}.
+func taskETagMatches(ifMatch string, taskJson []byte) bool {
+	for _, format := range responseFormats {
+		if etagMatchesStrong(ifMatch, taskETag(taskJson, format)) {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using
+// the weak comparison RFC 9110 specifies for it.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatchesStrong reports whether an If-Match header lists etag, using
+// the strong comparison RFC 9110 specifies for it: weak ETags never match.
+func etagMatchesStrong(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
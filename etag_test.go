@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestShowETagPerFormat(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+
+	etags := map[string]string{}
+	for _, format := range responseFormats {
+		rec := do(app, "GET", "/tasks/1?format="+format.name, "")
+		expectStatus(t, rec, http.StatusOK)
+		etag := rec.Header().Get("ETag")
+		for other, otherETag := range etags {
+			if etag == otherETag {
+				t.Errorf("%s and %s share the ETag %s", format.name, other, etag)
+			}
+		}
+		etags[format.name] = etag
+
+		req := newRequest("GET", "/tasks/1?format="+format.name, "")
+		req.Header.Set("If-None-Match", etag)
+		expectStatus(t, serve(app, req), http.StatusNotModified)
+	}
+}
+
+func TestIfMatchAcceptsETagFromAnyFormat(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+	etag := do(app, "GET", "/tasks/1?format=csv", "").Header().Get("ETag")
+
+	req := newRequest("PATCH", "/tasks/1", `{"Completed":true}`)
+	req.Header.Set("If-Match", etag)
+	expectStatus(t, serve(app, req), http.StatusOK)
+
+	// The task has changed since etag was sent.
+	req = newRequest("PATCH", "/tasks/1", `{"Title":"Water the plants"}`)
+	req.Header.Set("If-Match", etag)
+	expectStatus(t, serve(app, req), http.StatusPreconditionFailed)
+}
+
+func TestListETag(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+
+	rec := do(app, "GET", "/tasks", "")
+	expectStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("list has no ETag")
+	}
+
+	req := newRequest("GET", "/tasks", "")
+	req.Header.Set("If-None-Match", etag)
+	rec = serve(app, req)
+	expectStatus(t, rec, http.StatusNotModified)
+	if rec.Body.Len() != 0 {
+		t.Errorf("304 has a body %q", rec.Body.String())
+	}
+
+	// A new task changes the list, so the old ETag no longer matches.
+	createTask(t, app, `{"Title":"Feed cat"}`)
+	req = newRequest("GET", "/tasks", "")
+	req.Header.Set("If-None-Match", etag)
+	rec = serve(app, req)
+	expectStatus(t, rec, http.StatusOK)
+	if newETag := rec.Header().Get("ETag"); newETag == "" || newETag == etag {
+		t.Errorf("got ETag %q after a create, want a new one", newETag)
+	}
+	var tasks []Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 2 {
+		t.Errorf("listed %+v, want both tasks", tasks)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// export downloads every task matching the list filters in one file,
+// without list's paging. The format is negotiated like list's, so JSON
+// remains the default and ?format=csv gives a spreadsheet.
+func (app *application) export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/export", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	query, err := app.parseTaskQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format, err := negotiateFormat(r)
+	if err != nil {
+		var mr *malformedRequest
+		errors.As(err, &mr)
+		writeJSONError(w, mr.status, mr.msg)
+		return
+	}
+
+	tasks, err := app.store.List()
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while exporting tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	matched := []Task{}
+	for _, task := range tasks {
+		if query.matches(task) {
+			matched = append(matched, task)
+		}
+	}
+
+	// Rendering to a temporary file lets http.ServeContent answer Range
+	// and If-Range requests, so an interrupted download can resume, without
+	// holding a large export in memory.
+	file, err := os.CreateTemp("", "brain-export-*")
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while exporting tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	err = renderTasks(io.MultiWriter(file, hash), format, matched, app.clock.Now())
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while exporting tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// The ETag is what If-Range compares, so a resumed download only gets
+	// a partial response if the export hasn't changed since.
+	filename := "tasks." + format.extension
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("ETag", hashETag(hash))
+	w.Header().Set("Vary", "Accept")
+	http.ServeContent(w, r, filename, time.Time{}, file)
+}
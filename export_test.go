@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExportServesRanges(t *testing.T) {
+	app, _ := newTestApp(t)
+	for index := 1; index <= 5; index++ {
+		createTask(t, app, fmt.Sprintf(`{"Title":"Task %d"}`, index))
+	}
+
+	full := do(app, "GET", "/tasks/export?format=csv", "")
+	expectStatus(t, full, http.StatusOK)
+	if got := full.Header().Get("Content-Disposition"); got != `attachment; filename="tasks.csv"` {
+		t.Errorf("sent Content-Disposition %q", got)
+	}
+	if got := full.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("sent Accept-Ranges %q, want bytes", got)
+	}
+	body := full.Body.String()
+	etag := full.Header().Get("ETag")
+
+	req := newRequest("GET", "/tasks/export?format=csv", "")
+	req.Header.Set("Range", "bytes=10-")
+	req.Header.Set("If-Range", etag)
+	rec := serve(app, req)
+	expectStatus(t, rec, http.StatusPartialContent)
+	if got, want := rec.Header().Get("Content-Range"), fmt.Sprintf("bytes 10-%d/%d", len(body)-1, len(body)); got != want {
+		t.Errorf("sent Content-Range %q, want %q", got, want)
+	}
+	if rec.Body.String() != body[10:] {
+		t.Errorf("sent %q, want %q", rec.Body.String(), body[10:])
+	}
+
+	// Once the export changes, resuming from the old one gets it whole.
+	createTask(t, app, `{"Title":"Task 6"}`)
+	rec = serve(app, req)
+	expectStatus(t, rec, http.StatusOK)
+}
+
+func TestExportFilters(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Write report","Tags":["work"]}`)
+	createTask(t, app, `{"Title":"Water plants","Tags":["home"]}`)
+	createTask(t, app, `{"Title":"Book meeting room","Tags":["work","office"]}`)
+
+	rec := do(app, "GET", "/tasks/export?format=csv&tag=work", "")
+	expectStatus(t, rec, http.StatusOK)
+	records, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := []string{}
+	for _, record := range records[1:] {
+		titles = append(titles, record[slices.Index(records[0], "title")])
+	}
+	if !slices.Equal(titles, []string{"Write report", "Book meeting room"}) {
+		t.Errorf("exported %q, want only the work tasks", titles)
+	}
+
+	// Unlike list, export isn't paged, but its filters are validated the
+	// same way.
+	expectStatus(t, do(app, "GET", "/tasks/export?due_before=tomorrow", ""), http.StatusBadRequest)
+}
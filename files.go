@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// taskFS is the subset of filesystem operations used to read and write
+// task files.
+type taskFS interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	Remove(name string) error
+}
+
+type osFS struct{}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// WriteFile writes data to a temporary file in the same directory and
+// renames it over name, so a crash or full disk mid-write leaves the
+// previous contents intact instead of a truncated file.
+func (osFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// retryFS retries operations that fail with a transient error, such as
+// lock contention on a networked filesystem, doubling the backoff between
+// attempts. Permanent errors like a missing file are returned at once.
+type retryFS struct {
+	fs       taskFS
+	attempts int
+	backoff  time.Duration
+}
+
+func isTransientFSError(err error) bool {
+	return errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EBUSY) ||
+		errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.ETIMEDOUT)
+}
+
+func (rfs retryFS) retry(op func() error) error {
+	backoff := rfs.backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || !isTransientFSError(err) || attempt >= rfs.attempts {
+			return err
+		}
+
+		log.Printf("transient filesystem error, retrying in %s: %s", backoff, err.Error())
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (rfs retryFS) ReadFile(name string) ([]byte, error) {
+	var data []byte
+	err := rfs.retry(func() error {
+		var err error
+		data, err = rfs.fs.ReadFile(name)
+		return err
+	})
+	return data, err
+}
+
+func (rfs retryFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return rfs.retry(func() error {
+		return rfs.fs.WriteFile(name, data, perm)
+	})
+}
+
+func (rfs retryFS) Remove(name string) error {
+	return rfs.retry(func() error {
+		return rfs.fs.Remove(name)
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+// flakyFS is a taskFS whose writes fail with the errors in writeErrs, one
+// per call, before going through to osFS.
+type flakyFS struct {
+	osFS
+	writeErrs []error
+	writes    int
+}
+
+func (ffs *flakyFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	ffs.writes++
+	if len(ffs.writeErrs) > 0 {
+		err := ffs.writeErrs[0]
+		ffs.writeErrs = ffs.writeErrs[1:]
+		return err
+	}
+	return ffs.osFS.WriteFile(name, data, perm)
+}
+
+func TestRetryFSRetriesTransientErrors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "1.json")
+	flaky := &flakyFS{writeErrs: []error{&os.PathError{Op: "write", Path: name, Err: syscall.EAGAIN}}}
+	rfs := retryFS{fs: flaky, attempts: 3}
+
+	err := rfs.WriteFile(name, []byte(`{"Id":1}`), 0644)
+	if err != nil {
+		t.Fatalf("write failed after a transient error: %v", err)
+	}
+	if flaky.writes != 2 {
+		t.Errorf("wrote %d times, want 2", flaky.writes)
+	}
+	data, err := rfs.ReadFile(name)
+	if err != nil || string(data) != `{"Id":1}` {
+		t.Errorf("read %q, %v", data, err)
+	}
+}
+
+func TestRetryFSGivesUp(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "1.json")
+	busy := &os.PathError{Op: "write", Path: name, Err: syscall.EBUSY}
+	flaky := &flakyFS{writeErrs: []error{busy, busy, busy, busy}}
+	rfs := retryFS{fs: flaky, attempts: 3}
+
+	err := rfs.WriteFile(name, nil, 0644)
+	if !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("got %v, want EBUSY", err)
+	}
+	if flaky.writes != 3 {
+		t.Errorf("wrote %d times, want 3", flaky.writes)
+	}
+}
+
+func TestRetryFSFailsFastOnPermanentErrors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "1.json")
+	flaky := &flakyFS{writeErrs: []error{os.ErrPermission}}
+	rfs := retryFS{fs: flaky, attempts: 3}
+
+	err := rfs.WriteFile(name, nil, 0644)
+	if !errors.Is(err, os.ErrPermission) || flaky.writes != 1 {
+		t.Errorf("got %v after %d writes, want a permission error after 1", err, flaky.writes)
+	}
+
+	_, err = rfs.ReadFile(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v reading a missing file, want not exist", err)
+	}
+}
+
+func TestOSFSWriteFileReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "1.json")
+	for _, data := range []string{`{"Id":1,"Title":"Water plants"}`, `{"Id":1,"Title":"Feed cat"}`} {
+		err := osFS{}.WriteFile(name, []byte(data), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectFile(t, name, data)
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("got mode %v, want 0600", info.Mode().Perm())
+	}
+	expectOnlyFiles(t, dir, "1.json")
+}
+
+func TestOSFSWriteFileFailureKeepsOldContents(t *testing.T) {
+	// Renaming a file over a non-empty directory fails, after the
+	// temporary file has been written in full.
+	dir := t.TempDir()
+	name := filepath.Join(dir, "1.json")
+	err := os.Mkdir(name, 0755)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(name, "keep"), []byte("old"), 0644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = osFS{}.WriteFile(name, []byte(`{"Id":1}`), 0644)
+	if err == nil {
+		t.Fatal("writing over a directory succeeded")
+	}
+	expectFile(t, filepath.Join(name, "keep"), "old")
+	expectOnlyFiles(t, dir, "1.json")
+}
+
+// expectOnlyFiles fails the test unless dir holds exactly names, so no
+// temporary files were left behind.
+func expectOnlyFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if !slices.Equal(got, names) {
+		t.Errorf("%s holds %q, want %q", dir, got, names)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A filter expression narrows GET /tasks with a small boolean grammar:
+//
+//	expr := and ("OR" and)*
+//	and  := not ("AND" not)*
+//	not  := "NOT" not | "(" expr ")" | term
+//	term := field ":" value
+//
+// Keywords are case-insensitive. Values may be double-quoted to include
+// spaces, e.g. title:"buy milk". For example:
+//
+//	completed:false AND (title:milk OR title:eggs)
+
+type taskPredicate func(task Task) bool
+
+type filterError struct {
+	pos int
+	msg string
+}
+
+func (fe *filterError) Error() string {
+	return fmt.Sprintf("Invalid filter at position %d: %s", fe.pos, fe.msg)
+}
+
+type filterToken struct {
+	pos   int
+	text  string
+	field string
+	value string
+}
+
+type filterParser struct {
+	tokens []filterToken
+	next   int
+	end    int
+}
+
+func parseFilter(expr string) (taskPredicate, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, &filterError{pos: 0, msg: "empty expression"}
+	}
+
+	p := &filterParser{tokens: tokens, end: len(expr)}
+	predicate, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		return nil, &filterError{pos: tok.pos, msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+
+	return predicate, nil
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+
+	i := 0
+	for i < len(expr) {
+		switch c := expr[i]; {
+		case c == ' ' || c == '\t':
+			i++
+
+		case c == '(' || c == ')':
+			tokens = append(tokens, filterToken{pos: i, text: string(c)})
+			i++
+
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t()\"", rune(expr[i])) {
+				i++
+			}
+			if i < len(expr) && expr[i] == '"' {
+				closing := strings.IndexByte(expr[i+1:], '"')
+				if closing < 0 {
+					return nil, &filterError{pos: i, msg: "unterminated quote"}
+				}
+				i += closing + 2
+			}
+
+			text := expr[start:i]
+			tok := filterToken{pos: start, text: text}
+			if field, value, ok := strings.Cut(text, ":"); ok {
+				tok.field = strings.ToLower(field)
+				tok.value = strings.Trim(value, `"`)
+			}
+			tokens = append(tokens, tok)
+		}
+	}
+
+	return tokens, nil
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.next >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.next], true
+}
+
+func (p *filterParser) accept(keyword string) bool {
+	tok, ok := p.peek()
+	if ok && tok.field == "" && strings.EqualFold(tok.text, keyword) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (taskPredicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(task Task) bool { return l(task) || right(task) }
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (taskPredicate, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(task Task) bool { return l(task) && right(task) }
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (taskPredicate, error) {
+	if p.accept("NOT") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(task Task) bool { return !inner(task) }, nil
+	}
+
+	tok, ok := p.peek()
+	if !ok {
+		return nil, &filterError{pos: p.end, msg: "unexpected end of expression"}
+	}
+
+	if tok.text == "(" {
+		p.next++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, ok := p.peek()
+		if !ok || closing.text != ")" {
+			return nil, &filterError{pos: tok.pos, msg: "unclosed parenthesis"}
+		}
+		p.next++
+		return inner, nil
+	}
+
+	p.next++
+	return termPredicate(tok)
+}
+
+func termPredicate(tok filterToken) (taskPredicate, error) {
+	if tok.field == "" {
+		return nil, &filterError{pos: tok.pos, msg: fmt.Sprintf("expected field:value, got %q", tok.text)}
+	}
+
+	switch tok.field {
+	case "id":
+		id, err := strconv.Atoi(tok.value)
+		if err != nil {
+			return nil, &filterError{pos: tok.pos, msg: fmt.Sprintf("id must be an integer, got %q", tok.value)}
+		}
+		return func(task Task) bool { return task.Id == id }, nil
+
+	case "title":
+		return func(task Task) bool { return strings.Contains(task.Title, tok.value) }, nil
+
+	case "completed":
+		completed, err := strconv.ParseBool(tok.value)
+		if err != nil {
+			return nil, &filterError{pos: tok.pos, msg: fmt.Sprintf("completed must be true or false, got %q", tok.value)}
+		}
+		return func(task Task) bool { return task.Completed == completed }, nil
+
+	case "color":
+		color, err := normalizeColor(tok.value)
+		if err != nil {
+			return nil, &filterError{pos: tok.pos, msg: err.Error()}
+		}
+		return func(task Task) bool { return task.Color == color }, nil
+
+	case "priority":
+		priority, err := normalizePriority(tok.value)
+		if err != nil {
+			return nil, &filterError{pos: tok.pos, msg: err.Error()}
+		}
+		return func(task Task) bool { return task.Priority == priority }, nil
+
+	default:
+		return nil, &filterError{pos: tok.pos, msg: fmt.Sprintf("unknown field %q", tok.field)}
+	}
+}
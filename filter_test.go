@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestListFilter(t *testing.T) {
+	app, _ := newTestApp(t)
+	for _, body := range []string{
+		`{"Title":"Buy milk","Priority":"high"}`,
+		`{"Title":"Buy eggs","Priority":"low"}`,
+		`{"Title":"Fix sink","Priority":"high","Completed":true}`,
+		`{"Title":"Buy bread","Color":"red"}`,
+	} {
+		createTask(t, app, body)
+	}
+
+	tests := []struct {
+		filter string
+		want   []int
+	}{
+		{"completed:false AND priority:high", []int{1}},
+		{"priority:high OR color:red", []int{1, 3, 4}},
+		{"completed:false AND (title:milk OR title:eggs)", []int{1, 2}},
+		{"NOT title:Buy", []int{3}},
+		{`title:"Buy b"`, []int{4}},
+		{"id:2 or id:3 and completed:true", []int{2, 3}},
+	}
+	for _, test := range tests {
+		target := "/tasks?filter=" + url.QueryEscape(test.filter)
+		if got := listIds(t, app, target); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.filter, got, test.want)
+		}
+	}
+}
+
+func TestListFilterErrors(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{"completed:false AND", "Invalid filter at position 19: unexpected end of expression"},
+		{"(title:milk", "Invalid filter at position 0: unclosed parenthesis"},
+		{"owner:me", `Invalid filter at position 0: unknown field "owner"`},
+		{`title:"milk`, "Invalid filter at position 6: unterminated quote"},
+		{"title:milk title:eggs", `Invalid filter at position 11: unexpected "title:eggs"`},
+	}
+	for _, test := range tests {
+		target := "/tasks?filter=" + url.QueryEscape(test.filter)
+		rec := do(app, "GET", target, "")
+		expectStatus(t, rec, http.StatusBadRequest)
+		var res errorResponse
+		decode(t, rec, &res)
+		if res.Error != test.want {
+			t.Errorf("%s: got error %q, want %q", test.filter, res.Error, test.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultFocusPath = "focus.json"
+
+// focusStore keeps each user's focus list, the task IDs they pinned in the
+// order they pinned them. Lists are kept in one file outside the tasks
+// directory so they never show up as tasks.
+type focusStore struct {
+	mu    sync.Mutex
+	path  string
+	files taskFS
+}
+
+func (fs *focusStore) load() (map[string][]int, error) {
+	lists := make(map[string][]int)
+
+	listsJson, err := fs.files.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lists, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(listsJson, &lists)
+	return lists, err
+}
+
+func (fs *focusStore) save(lists map[string][]int) error {
+	listsJson, err := json.Marshal(lists)
+	if err != nil {
+		return err
+	}
+	return fs.files.WriteFile(fs.path, listsJson, 0644)
+}
+
+// ids returns user's focus list.
+func (fs *focusStore) ids(user string) ([]int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	lists, err := fs.load()
+	if err != nil {
+		return nil, err
+	}
+	return lists[user], nil
+}
+
+// add appends taskId to user's focus list unless it is already there.
+func (fs *focusStore) add(user string, taskId int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	lists, err := fs.load()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(lists[user], taskId) {
+		return nil
+	}
+
+	lists[user] = append(lists[user], taskId)
+	return fs.save(lists)
+}
+
+// remove drops taskId from user's focus list, reporting whether it was
+// there.
+func (fs *focusStore) remove(user string, taskId int) (bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	lists, err := fs.load()
+	if err != nil {
+		return false, err
+	}
+
+	index := slices.Index(lists[user], taskId)
+	if index < 0 {
+		return false, nil
+	}
+
+	lists[user] = slices.Delete(lists[user], index, index+1)
+	return true, fs.save(lists)
+}
+
+// focus returns the authenticated user's pinned tasks in the order they
+// were pinned. Pinned tasks that have since been deleted are skipped.
+func (app *application) focus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		msg := fmt.Sprintf("Unsupported request method %v to /focus", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	user, _, _ := r.BasicAuth()
+	ids, err := app.focusLists.ids(user)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving your focus list, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	pinned := []json.RawMessage{}
+	for _, taskId := range ids {
+		taskJson, err := app.readTaskJson(taskId)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while retrieving your focus list, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		pinned = append(pinned, taskJson)
+	}
+
+	pinnedJson, err := json.Marshal(pinned)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving your focus list, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(pinnedJson)
+}
+
+func (app *application) focusTask(w http.ResponseWriter, r *http.Request) {
+	idPart := strings.TrimPrefix(r.URL.Path, "/focus/")
+	if strings.Contains(idPart, "/") {
+		msg := fmt.Sprintf("No route for %v", r.URL.Path)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
+	taskId, err := strconv.Atoi(idPart)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid task ID: %v", idPart)
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	user, _, _ := r.BasicAuth()
+
+	switch r.Method {
+	case "POST":
+		_, err = app.readTask(taskId)
+		if errors.Is(err, os.ErrNotExist) {
+			msg := fmt.Sprintf("Task with ID %v not found", taskId)
+			writeJSONError(w, http.StatusNotFound, msg)
+			return
+		}
+		if err == nil {
+			err = app.focusLists.add(user, taskId)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while updating your focus list, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case "DELETE":
+		found, err := app.focusLists.remove(user, taskId)
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while updating your focus list, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		if !found {
+			msg := fmt.Sprintf("Task with ID %v is not in your focus list", taskId)
+			writeJSONError(w, http.StatusNotFound, msg)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		msg := fmt.Sprintf("Unsupported request method %v to /focus/", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "POST, DELETE")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// focusIds returns the IDs of the tasks in the test user's focus list.
+func focusIds(t *testing.T, app *application) []int {
+	t.Helper()
+
+	rec := do(app, "GET", "/focus", "")
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []Task
+	decode(t, rec, &tasks)
+	ids := []int{}
+	for _, task := range tasks {
+		ids = append(ids, task.Id)
+	}
+	return ids
+}
+
+func TestFocus(t *testing.T) {
+	app, _ := newTestApp(t)
+	for _, title := range []string{"Water plants", "Feed cat", "Walk dog"} {
+		createTask(t, app, `{"Title":"`+title+`"}`)
+	}
+
+	if got := focusIds(t, app); len(got) != 0 {
+		t.Errorf("new focus list has %v", got)
+	}
+
+	// Tasks keep the order they were pinned in, and pinning twice is a
+	// no-op.
+	for _, taskId := range []string{"3", "1", "3"} {
+		expectStatus(t, do(app, "POST", "/focus/"+taskId, ""), http.StatusNoContent)
+	}
+	if got, want := focusIds(t, app), []int{3, 1}; !slices.Equal(got, want) {
+		t.Errorf("focus list %v, want %v", got, want)
+	}
+
+	expectStatus(t, do(app, "POST", "/focus/9", ""), http.StatusNotFound)
+	expectStatus(t, do(app, "POST", "/focus/one", ""), http.StatusBadRequest)
+	expectStatus(t, do(app, "POST", "/focus/", ""), http.StatusBadRequest)
+	expectStatus(t, do(app, "POST", "/focus/x/3", ""), http.StatusNotFound)
+	expectStatus(t, do(app, "DELETE", "/focus/3/", ""), http.StatusNotFound)
+
+	expectStatus(t, do(app, "DELETE", "/focus/3", ""), http.StatusNoContent)
+	expectStatus(t, do(app, "DELETE", "/focus/3", ""), http.StatusNotFound)
+	if got, want := focusIds(t, app), []int{1}; !slices.Equal(got, want) {
+		t.Errorf("focus list %v after removing 3, want %v", got, want)
+	}
+
+	// Deleted tasks drop out of the list.
+	expectStatus(t, do(app, "POST", "/focus/2", ""), http.StatusNoContent)
+	expectStatus(t, do(app, "DELETE", "/tasks/1", ""), http.StatusNoContent)
+	if got, want := focusIds(t, app), []int{2}; !slices.Equal(got, want) {
+		t.Errorf("focus list %v after deleting 1, want %v", got, want)
+	}
+}
+
+func TestFocusStorePerUser(t *testing.T) {
+	dir := t.TempDir()
+	focusPath := filepath.Join(dir, defaultFocusPath)
+	lists := &focusStore{path: focusPath, files: osFS{}}
+
+	for user, ids := range map[string][]int{"alice": {1, 2}, "bob": {2}} {
+		for _, taskId := range ids {
+			err := lists.add(user, taskId)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	found, err := lists.remove("bob", 2)
+	if err != nil || !found {
+		t.Fatalf("removing bob's 2: found %v, error %v", found, err)
+	}
+
+	// Lists survive a restart, all kept in the one file.
+	lists = &focusStore{path: focusPath, files: osFS{}}
+	ids, err := lists.ids("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []int{1, 2}) {
+		t.Errorf("alice's list %v, want [1 2]", ids)
+	}
+	ids, err = lists.ids("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("bob's list %v, want it empty", ids)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != defaultFocusPath {
+		t.Errorf("focus store wrote %v", entries)
+	}
+}
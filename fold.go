@@ -0,0 +1,30 @@
+package main
+
+import (
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Casers and transformers are stateful and can't be shared between
+// goroutines, so each call builds its own.
+
+// foldCase returns s case-folded, for matching that ignores case.
+func foldCase(s string) string {
+	return cases.Fold().String(s)
+}
+
+// foldText returns s case-folded and with accents removed, for matching
+// that ignores both. Text is decomposed (NFD) and the combining marks
+// dropped, so "café" becomes "cafe".
+func foldText(s string) string {
+	stripMarks := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(stripMarks, s)
+	if err != nil {
+		stripped = s
+	}
+	return foldCase(stripped)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestFoldText(t *testing.T) {
+	tests := map[string]string{
+		"Café":         "cafe",
+		"Cafe\u0301":   "cafe",
+		"crème BRÛLÉE": "creme brulee",
+		"Straße":       "strasse",
+		"naïve":        "naive",
+	}
+	for s, want := range tests {
+		if got := foldText(s); got != want {
+			t.Errorf("foldText(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestSearchFold(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Visit the café"}`)
+	createTask(t, app, `{"Title":"Visit the CAFÉ again"}`)
+	createTask(t, app, `{"Title":"Visit the cafe"}`)
+
+	search := func(q, fold string) []int {
+		return listIds(t, app, "/tasks?q="+url.QueryEscape(q)+"&fold="+fold)
+	}
+	if got, want := search("CAFE", "true"), []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("folded search for CAFE got %v, want %v", got, want)
+	}
+	if got, want := search("café", "true"), []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("folded search for café got %v, want %v", got, want)
+	}
+
+	// Without fold, case is still ignored, but accents are not.
+	if got, want := search("CAFE", "false"), []int{3}; !slices.Equal(got, want) {
+		t.Errorf("search for CAFE got %v, want %v", got, want)
+	}
+	if got, want := search("café", "false"), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("search for café got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type responseFormat struct {
+	name        string
+	contentType string
+	extension   string
+}
+
+var (
+	formatJSON     = responseFormat{"json", "application/json", "json"}
+	formatNDJSON   = responseFormat{"ndjson", "application/x-ndjson", "ndjson"}
+	formatCSV      = responseFormat{"csv", "text/csv", "csv"}
+	formatMarkdown = responseFormat{"markdown", "text/markdown", "md"}
+	formatCalendar = responseFormat{"ics", "text/calendar", "ics"}
+)
+
+// responseFormats are listed in order of preference, which decides what a
+// wildcard Accept like */* or text/* resolves to.
+var responseFormats = []responseFormat{formatJSON, formatNDJSON, formatCSV, formatMarkdown, formatCalendar}
+
+// negotiateFormat picks the response format for a request: ?format= wins if
+// present, otherwise the most preferred supported type in the Accept
+// header. No Accept header means JSON. The error is a *malformedRequest
+// with status 400 for an unknown ?format= and 406 for an unsatisfiable
+// Accept.
+func negotiateFormat(r *http.Request) (responseFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, format := range responseFormats {
+			if format.name == name {
+				return format, nil
+			}
+		}
+		names := make([]string, len(responseFormats))
+		for index, format := range responseFormats {
+			names[index] = format.name
+		}
+		msg := fmt.Sprintf("Invalid format %q, must be one of %s", name, strings.Join(names, ", "))
+		return formatJSON, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return formatJSON, nil
+	}
+
+	type acceptedRange struct {
+		mediaRange string
+		q          float64
+	}
+	var ranges []acceptedRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(part, ";")
+		ar := acceptedRange{mediaRange: strings.ToLower(strings.TrimSpace(mediaRange)), q: 1}
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				q, err := strconv.ParseFloat(value, 64)
+				if err == nil {
+					ar.q = q
+				}
+			}
+		}
+		if ar.q > 0 {
+			ranges = append(ranges, ar)
+		}
+	}
+	slices.SortStableFunc(ranges, func(a, b acceptedRange) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	for _, ar := range ranges {
+		for _, format := range responseFormats {
+			if mediaRangeMatches(ar.mediaRange, format.contentType) {
+				return format, nil
+			}
+		}
+	}
+
+	msg := fmt.Sprintf("None of the accepted types %q are supported", accept)
+	return formatJSON, &malformedRequest{status: http.StatusNotAcceptable, msg: msg}
+}
+
+func mediaRangeMatches(mediaRange, contentType string) bool {
+	if mediaRange == "*/*" || mediaRange == contentType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(contentType, prefix+"/")
+}
+
+// writeTasks renders tasks in format. JSON is an array; single-task
+// responses that want a bare object should use json.Marshal directly.
+func writeTasks(w http.ResponseWriter, format responseFormat, tasks []Task, now time.Time) error {
+	w.Header().Set("Content-Type", format.contentType)
+	return renderTasks(w, format, tasks, now)
+}
+
+// renderTasks is writeTasks for any io.Writer, without setting headers.
+func renderTasks(w io.Writer, format responseFormat, tasks []Task, now time.Time) error {
+	switch format {
+	case formatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, task := range tasks {
+			err := enc.Encode(task)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case formatCSV:
+		return writeTasksCSV(w, tasks)
+
+	case formatMarkdown:
+		for _, task := range tasks {
+			check := " "
+			if task.Completed {
+				check = "x"
+			}
+			_, err := fmt.Fprintf(w, "- [%s] %s\n", check, strings.Join(strings.Fields(task.Title), " "))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case formatCalendar:
+		return writeTasksCalendar(w, tasks, now)
+
+	default:
+		tasksJson, err := json.Marshal(tasks)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(tasksJson)
+		return err
+	}
+}
+
+func writeTasksCSV(w io.Writer, tasks []Task) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"id", "title", "completed", "color", "priority", "start_date", "due", "tags", "parent", "created", "updated"})
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		startDate := ""
+		if task.StartDate != nil {
+			startDate = task.StartDate.Format(time.RFC3339)
+		}
+		due := ""
+		if task.Due != nil {
+			due = task.Due.Format(time.RFC3339)
+		}
+		parent := ""
+		if task.ParentId != nil {
+			parent = strconv.Itoa(*task.ParentId)
+		}
+		err = cw.Write([]string{
+			strconv.Itoa(task.Id),
+			task.Title,
+			strconv.FormatBool(task.Completed),
+			task.Color,
+			task.Priority,
+			startDate,
+			due,
+			strings.Join(task.Tags, ","),
+			parent,
+			csvTime(task.CreatedAt),
+			csvTime(task.UpdatedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvTime formats t for CSV, leaving the zero time blank.
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// icalPriorities maps priorities onto RFC 5545's 1 (highest) to 9 scale.
+var icalPriorities = map[string]string{"high": "1", "medium": "5", "low": "9"}
+
+// writeTasksCalendar renders tasks as iCalendar (RFC 5545) VTODO entries.
+func writeTasksCalendar(w io.Writer, tasks []Task, now time.Time) error {
+	const icalTime = "20060102T150405Z"
+
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//brain//tasks//EN"}
+	for _, task := range tasks {
+		status := "NEEDS-ACTION"
+		if task.Completed {
+			status = "COMPLETED"
+		}
+		lines = append(lines,
+			"BEGIN:VTODO",
+			fmt.Sprintf("UID:task-%d@brain", task.Id),
+			"DTSTAMP:"+now.UTC().Format(icalTime),
+			"SUMMARY:"+escapeICalText(task.Title),
+			"STATUS:"+status,
+			"PRIORITY:"+icalPriorities[task.Priority],
+		)
+		if task.StartDate != nil {
+			lines = append(lines, "DTSTART:"+task.StartDate.UTC().Format(icalTime))
+		}
+		if task.Due != nil {
+			lines = append(lines, "DUE:"+task.Due.UTC().Format(icalTime))
+		}
+		if task.ParentId != nil {
+			lines = append(lines, fmt.Sprintf("RELATED-TO:task-%d@brain", *task.ParentId))
+		}
+		if !task.CreatedAt.IsZero() {
+			lines = append(lines, "CREATED:"+task.CreatedAt.UTC().Format(icalTime))
+		}
+		if !task.UpdatedAt.IsZero() {
+			lines = append(lines, "LAST-MODIFIED:"+task.UpdatedAt.UTC().Format(icalTime))
+		}
+		if len(task.Tags) > 0 {
+			categories := make([]string, len(task.Tags))
+			for index, tag := range task.Tags {
+				categories[index] = escapeICalText(tag)
+			}
+			lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+		}
+		lines = append(lines, "END:VTODO")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		_, err := io.WriteString(w, foldICalLine(line)+"\r\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICalLine splits lines longer than 75 octets as RFC 5545 requires,
+// without breaking UTF-8 sequences.
+func foldICalLine(line string) string {
+	var folded strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			folded.WriteString("\r\n ")
+			width = 1
+		}
+		folded.WriteRune(r)
+		width += size
+	}
+	return folded.String()
+}
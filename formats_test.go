@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestListNegotiatesFormat(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+
+	tests := []struct {
+		target, accept string
+		status         int
+		contentType    string
+	}{
+		{"/tasks", "", http.StatusOK, "application/json"},
+		{"/tasks", "*/*", http.StatusOK, "application/json"},
+		{"/tasks", "text/csv", http.StatusOK, "text/csv"},
+		{"/tasks", "text/*", http.StatusOK, "text/csv"},
+		{"/tasks", "application/json;q=0.5, text/markdown", http.StatusOK, "text/markdown"},
+		{"/tasks", "text/csv;q=0, application/x-ndjson;q=0.1", http.StatusOK, "application/x-ndjson"},
+		{"/tasks", "TEXT/CALENDAR", http.StatusOK, "text/calendar"},
+		{"/tasks", "image/png", http.StatusNotAcceptable, "application/json"},
+		{"/tasks", "text/csv;q=0", http.StatusNotAcceptable, "application/json"},
+		{"/tasks?format=csv", "application/json", http.StatusOK, "text/csv"},
+		{"/tasks?format=xml", "", http.StatusBadRequest, "application/json"},
+	}
+	for _, test := range tests {
+		req := newRequest("GET", test.target, "")
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		rec := serve(app, req)
+		if rec.Code != test.status {
+			t.Errorf("%s with Accept %q: got status %d, want %d", test.target, test.accept, rec.Code, test.status)
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, test.contentType) {
+			t.Errorf("%s with Accept %q: got Content-Type %q, want %q", test.target, test.accept, got, test.contentType)
+		}
+		if rec.Code == http.StatusOK && rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s with Accept %q: got Vary %q, want Accept", test.target, test.accept, rec.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestNotAcceptableListsAccept(t *testing.T) {
+	app, _ := newTestApp(t)
+	req := newRequest("GET", "/tasks", "")
+	req.Header.Set("Accept", "image/png")
+
+	rec := serve(app, req)
+	expectStatus(t, rec, http.StatusNotAcceptable)
+	var res errorResponse
+	decode(t, rec, &res)
+	if !strings.Contains(res.Error, `"image/png"`) {
+		t.Errorf("got error %q, want it to name the accepted types", res.Error)
+	}
+}
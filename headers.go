@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// defaultSecurityHeaders are sent on every response. Task titles are user
+// controlled, so browsers must not be allowed to sniff responses into
+// something executable or frame them.
+var defaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options":  "nosniff",
+	"X-Frame-Options":         "DENY",
+	"Referrer-Policy":         "no-referrer",
+	"Content-Security-Policy": "default-src 'self'; frame-ancestors 'none'",
+}
+
+// parseSecurityHeaders merges overrides, a JSON object of header names to
+// values, over the defaults. An empty value drops that header.
+func parseSecurityHeaders(overrides string) (map[string]string, error) {
+	headers := make(map[string]string, len(defaultSecurityHeaders))
+	for name, value := range defaultSecurityHeaders {
+		headers[name] = value
+	}
+
+	if overrides == "" {
+		return headers, nil
+	}
+
+	var custom map[string]string
+	err := json.Unmarshal([]byte(overrides), &custom)
+	if err != nil {
+		return nil, fmt.Errorf("BRAIN_SECURITY_HEADERS must be a JSON object of header names to values: %w", err)
+	}
+
+	for name, value := range custom {
+		name = http.CanonicalHeaderKey(name)
+		if value == "" {
+			delete(headers, name)
+		} else {
+			headers[name] = value
+		}
+	}
+
+	return headers, nil
+}
+
+func (app *application) securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range app.headers {
+			w.Header().Set(name, value)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
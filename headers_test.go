@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	// Errors and unauthenticated responses get the headers too.
+	for _, req := range []*http.Request{
+		newRequest("GET", "/tasks", ""),
+		newRequest("GET", "/tasks/99", ""),
+		newRequest("GET", "/healthz", ""),
+		httptest.NewRequest("GET", "/tasks", nil),
+	} {
+		rec := serve(app, req)
+		for name, value := range defaultSecurityHeaders {
+			if got := rec.Header().Get(name); got != value {
+				t.Errorf("%s %s (status %d): %s is %q, want %q", req.Method, req.URL, rec.Code, name, got, value)
+			}
+		}
+	}
+}
+
+func TestParseSecurityHeaders(t *testing.T) {
+	headers, err := parseSecurityHeaders(`{"x-frame-options":"SAMEORIGIN","Referrer-Policy":"","Permissions-Policy":"camera=()"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers["X-Frame-Options"] != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options is %q, want the override", headers["X-Frame-Options"])
+	}
+	if _, ok := headers["Referrer-Policy"]; ok {
+		t.Error("an empty value didn't drop Referrer-Policy")
+	}
+	if headers["Permissions-Policy"] != "camera=()" || headers["X-Content-Type-Options"] != "nosniff" {
+		t.Errorf("got %v, want the additions and remaining defaults", headers)
+	}
+
+	_, err = parseSecurityHeaders(`["nosniff"]`)
+	if err == nil {
+		t.Error("accepted a JSON array")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthz is a liveness and readiness probe. It is registered without
+// basicAuth so orchestrators don't need credentials, and reports 503
+// unless the task store accepts writes. It reports "degraded" while
+// BRAIN_STORE_FALLBACK has swapped in the file store.
+func (app *application) healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		msg := fmt.Sprintf("Unsupported request method %v to /healthz", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	status := http.StatusOK
+	res := healthResponse{Status: "ok"}
+	// A fallback store still serves requests, so it isn't a 503, but the
+	// configured store needs fixing.
+	if app.storeFallback != nil {
+		res = healthResponse{Status: "degraded", Error: app.storeFallback.Error()}
+	}
+	err := app.store.Ping()
+	if err != nil {
+		log.Printf("health check failed: %v", err)
+		status = http.StatusServiceUnavailable
+		res = healthResponse{Status: "unavailable", Error: err.Error()}
+	}
+
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Probes poll, so a cached answer would hide an outage.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(resJson)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHealthz(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	rec := do(app, "GET", "/healthz", "")
+	expectStatus(t, rec, http.StatusOK)
+	var res healthResponse
+	decode(t, rec, &res)
+	if res.Status != "ok" {
+		t.Errorf("got %+v, want ok", res)
+	}
+}
+
+func TestStoreFallback(t *testing.T) {
+	// The database's directory doesn't exist, so SQLite can't create it.
+	badPath := filepath.Join(t.TempDir(), "missing", "brain.db")
+	t.Setenv("BRAIN_SQLITE_PATH", badPath)
+	t.Setenv("TASKS_DIR", filepath.Join(t.TempDir(), "tasks"))
+
+	app, _ := newTestApp(t)
+	err := app.openStore("sqlite", false)
+	if err == nil {
+		t.Fatal("opened a database in a missing directory")
+	}
+
+	logs := captureLogs(t)
+	err = app.openStore("sqlite", true)
+	if err != nil {
+		t.Fatalf("falling back: %v", err)
+	}
+	if _, ok := app.store.(*FileStore); !ok {
+		t.Fatalf("store is %T, want the file store", app.store)
+	}
+	if !strings.Contains(logs.String(), "falling back to the file store") {
+		t.Errorf("logs %q don't mention the fallback", logs.String())
+	}
+	createTask(t, app, `{"Title":"Buy milk"}`)
+
+	rec := do(app, "GET", "/healthz", "")
+	expectStatus(t, rec, http.StatusOK)
+	var res healthResponse
+	decode(t, rec, &res)
+	if res.Status != "degraded" || !strings.Contains(res.Error, "sqlite") {
+		t.Errorf("got %+v, want degraded with the sqlite error", res)
+	}
+
+	rec = do(app, "GET", "/admin/config", "")
+	expectStatus(t, rec, http.StatusOK)
+	var config configResponse
+	decode(t, rec, &config)
+	if config.Store.Type != "files" || config.Store.FallbackError == "" {
+		t.Errorf("store config is %+v, want files with the fallback error", config.Store)
+	}
+}
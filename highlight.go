@@ -0,0 +1,54 @@
+package main
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// highlight returns title as HTML with every match of the query's search
+// wrapped in <mark>. The whole title is escaped, not just the matches, so
+// clients can insert it as markup as-is. Matching folds the title the same
+// way matches does, one substring at a time, so the marks land on the
+// original text even when folding changes its length.
+func (query taskQuery) highlight(title string) string {
+	fold := foldCase
+	if query.fold {
+		fold = foldText
+	}
+
+	var highlighted strings.Builder
+	plainStart := 0
+	for start := 0; start < len(title); {
+		end := query.matchEnd(title, start, fold)
+		if end < 0 {
+			_, size := utf8.DecodeRuneInString(title[start:])
+			start += size
+			continue
+		}
+		highlighted.WriteString(html.EscapeString(title[plainStart:start]))
+		highlighted.WriteString("<mark>" + html.EscapeString(title[start:end]) + "</mark>")
+		start, plainStart = end, end
+	}
+	highlighted.WriteString(html.EscapeString(title[plainStart:]))
+
+	return highlighted.String()
+}
+
+// matchEnd returns the end of the shortest match of the search starting at
+// title[start:], or -1 if there is none.
+func (query taskQuery) matchEnd(title string, start int, fold func(string) string) int {
+	for end := start; end < len(title); {
+		_, size := utf8.DecodeRuneInString(title[end:])
+		end += size
+
+		folded := fold(title[start:end])
+		if folded == query.search {
+			return end
+		}
+		if !strings.HasPrefix(query.search, folded) {
+			return -1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		title, search string
+		fold          bool
+		want          string
+	}{
+		{"Buy milk", "milk", false, "Buy <mark>milk</mark>"},
+		{"Milk, more MILK", "milk", false, "<mark>Milk</mark>, more <mark>MILK</mark>"},
+		{"aaa", "aa", false, "<mark>aa</mark>a"},
+		{"Fix <b> & </b> tags", "<b>", false, "Fix <mark>&lt;b&gt;</mark> &amp; &lt;/b&gt; tags"},
+		{`Say "hi" & go`, "go", false, "Say &#34;hi&#34; &amp; <mark>go</mark>"},
+		{"Visit the Café", "cafe", true, "Visit the <mark>Café</mark>"},
+		{"Visit the Café", "cafe", false, "Visit the Café"},
+		{"Straße", "strasse", true, "<mark>Straße</mark>"},
+	}
+	for _, test := range tests {
+		query := taskQuery{search: foldCase(test.search), fold: test.fold}
+		if test.fold {
+			query.search = foldText(test.search)
+		}
+		if got := query.highlight(test.title); got != test.want {
+			t.Errorf("highlighting %q in %q: got %q, want %q", test.search, test.title, got, test.want)
+		}
+	}
+}
+
+func TestListHighlight(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Buy <milk> & eggs"}`)
+
+	rec := do(app, "GET", "/tasks?q=milk&highlight=true", "")
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "Buy &lt;<mark>milk</mark>&gt; &amp; eggs" {
+		t.Errorf("got %+v, want the match marked and the rest escaped", tasks)
+	}
+
+	// Without highlight=true, titles come back as stored.
+	decode(t, do(app, "GET", "/tasks?q=milk", ""), &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "Buy <milk> & eggs" {
+		t.Errorf("got %+v, want the title unchanged", tasks)
+	}
+}
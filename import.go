@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+type importResult struct {
+	Index int    `json:"index"`
+	Task  *Task  `json:"task,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type importValidation struct {
+	Valid   bool           `json:"valid"`
+	Results []importResult `json:"results"`
+}
+
+// validateImport previews creating a batch of tasks: each one goes through
+// the same normalization and checks as POST /tasks, but nothing is saved.
+// Every task is reported, normalized or with the reason it was rejected,
+// so clients can show all the problems at once.
+func (app *application) validateImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/import/validate", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	var bodies []newTaskBody
+	err := decodeJsonBody(w, r, &bodies)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			log.Print(err.Error())
+			writeJSONError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		return
+	}
+
+	res := importValidation{Valid: true, Results: []importResult{}}
+	for index, body := range bodies {
+		result := importResult{Index: index}
+		task, err := app.applyDueText(body.Task, body.DueText)
+		if err == nil {
+			task, err = app.prepareTask(w, task)
+		}
+
+		var mr *malformedRequest
+		switch {
+		case errors.As(err, &mr):
+			result.Error = mr.msg
+			res.Valid = false
+		case err != nil:
+			msg := fmt.Sprintf("An error occurred while validating your tasks, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		default:
+			result.Task = &task
+		}
+		res.Results = append(res.Results, result)
+	}
+
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while validating your tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resJson)
+}
+
+// checklistItem matches a markdown task list item, like "- [ ] Buy milk"
+// or "  * [x] Done", capturing the indentation, the check and the title.
+var checklistItem = regexp.MustCompile(`^([ \t]*)[-*+] \[([ xX])\] (.*)$`)
+
+type markdownImport struct {
+	Imported int    `json:"imported"`
+	Tasks    []Task `json:"tasks"`
+}
+
+// markdownTask is a checklist item, with the index of the item it is
+// nested under, or -1 at the top level, and how many levels deep that is.
+type markdownTask struct {
+	line   int
+	task   Task
+	parent int
+	depth  int
+}
+
+// parseChecklist reads the task list items in markdown, ignoring any other
+// lines. An item indented further than the one before it is its subtask.
+// Tabs count as four spaces.
+func parseChecklist(markdown []byte) []markdownTask {
+	type level struct{ indent, index int }
+	var items []markdownTask
+	var stack []level
+
+	scanner := bufio.NewScanner(bytes.NewReader(markdown))
+	for line := 1; scanner.Scan(); line++ {
+		match := checklistItem.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		indent := len(strings.ReplaceAll(match[1], "\t", "    "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		item := markdownTask{
+			line:   line,
+			task:   Task{Title: match[3], Completed: match[2] != " "},
+			parent: -1,
+		}
+		if len(stack) > 0 {
+			item.parent = stack[len(stack)-1].index
+			item.depth = len(stack)
+		}
+		stack = append(stack, level{indent: indent, index: len(items)})
+		items = append(items, item)
+	}
+	return items
+}
+
+// importMarkdown creates a task for every item of a markdown checklist,
+// keeping its nesting as subtasks. Either every item is imported or none.
+func (app *application) importMarkdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/import/markdown", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		msg := fmt.Sprintf("Could not read request body, %q", err.Error())
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+	items := parseChecklist(body)
+	if len(items) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "Request body has no checklist items like - [ ] task")
+		return
+	}
+
+	app.createMu.Lock()
+	defer app.createMu.Unlock()
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
+
+	tasks := make([]Task, len(items))
+	for index, item := range items {
+		tasks[index], err = app.prepareTask(w, item.task)
+		if err == nil {
+			err = app.checkSubtaskDepth(item.depth)
+		}
+		if err != nil {
+			var mr *malformedRequest
+			if errors.As(err, &mr) {
+				msg := fmt.Sprintf("Line %d: %s", item.line, mr.msg)
+				writeJSONError(w, mr.status, msg)
+			} else {
+				msg := fmt.Sprintf("An error occurred while importing your tasks, %q", err.Error())
+				writeJSONError(w, http.StatusInternalServerError, msg)
+			}
+			return
+		}
+	}
+
+	err = app.checkTaskCount(w, len(tasks))
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			msg := fmt.Sprintf("An error occurred while importing your tasks, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+		}
+		return
+	}
+
+	// IDs are only known once the tasks are created, so subtasks get their
+	// parents afterwards. Holding updateMu keeps anyone from seeing them
+	// half-linked, and a failure deletes the whole import again.
+	tasks, err = app.store.Create(tasks)
+	if err == nil {
+		err = linkChecklist(app.store, tasks, items)
+	}
+	if err != nil {
+		for _, task := range tasks {
+			app.store.Delete(task.Id)
+		}
+		msg := fmt.Sprintf("An error occurred while importing your tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+	for _, task := range tasks {
+		app.sessions.touch(sessionId(r), task.Id, app.clock.Now())
+	}
+
+	resJson, err := json.Marshal(markdownImport{Imported: len(tasks), Tasks: tasks})
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while importing your tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resJson)
+}
+
+// linkChecklist sets the parents of the tasks created from items.
+func linkChecklist(store TaskStore, tasks []Task, items []markdownTask) error {
+	for index, item := range items {
+		if item.parent < 0 {
+			continue
+		}
+		parentId := tasks[item.parent].Id
+		tasks[index].ParentId = &parentId
+		err := store.Update(tasks[index])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestValidateImport(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Plan trip"}`)
+
+	rec := do(app, "POST", "/tasks/import/validate", `[
+		{"Title":"Book flights","Tags":[" Travel ","travel"],"Priority":"HIGH","ParentId":1},
+		{"Title":"   "},
+		{"Title":"Pack","Color":"not-a-color"},
+		{"Title":"Book hotel"},
+		{"Title":"Unpack","ParentId":99}
+	]`)
+	expectStatus(t, rec, http.StatusOK)
+	var res importValidation
+	decode(t, rec, &res)
+
+	if res.Valid {
+		t.Error("reported valid with invalid tasks")
+	}
+	if len(res.Results) != 5 {
+		t.Fatalf("got %d results, want 5: %+v", len(res.Results), res.Results)
+	}
+	for index, result := range res.Results {
+		if result.Index != index {
+			t.Errorf("result %d has index %d", index, result.Index)
+		}
+		if wantValid := index == 0 || index == 3; wantValid != (result.Task != nil) || wantValid == (result.Error != "") {
+			t.Errorf("result %d is %+v, want valid %v", index, result, wantValid)
+		}
+	}
+	if task := res.Results[0].Task; task.Priority != "high" || !reflect.DeepEqual(task.Tags, []string{"travel"}) {
+		t.Errorf("first task is %+v, want it normalized", task)
+	}
+	if task := res.Results[3].Task; task.Priority != defaultPriority {
+		t.Errorf("fourth task has priority %q, want the default", task.Priority)
+	}
+
+	tasks, err := app.store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Errorf("store has %d tasks after validating, want 1", len(tasks))
+	}
+}
+
+func TestImportMarkdown(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Existing"}`)
+
+	markdown := "# Trip\n" +
+		"- [ ] Plan trip\n" +
+		"  - [x] Book flights\n" +
+		"    - [X] Compare prices\n" +
+		"  - [ ] Book hotel\n" +
+		"\n" +
+		"Some notes that aren't tasks.\n" +
+		"* [ ] Buy milk\n" +
+		"\t- [ ] Semi-skimmed\n"
+	rec := do(app, "POST", "/tasks/import/markdown", markdown)
+	expectStatus(t, rec, http.StatusOK)
+	var res markdownImport
+	decode(t, rec, &res)
+	if res.Imported != 6 {
+		t.Fatalf("imported %d, want 6: %+v", res.Imported, res.Tasks)
+	}
+
+	want := []struct {
+		title     string
+		completed bool
+		parent    int
+	}{
+		{"Plan trip", false, 0},
+		{"Book flights", true, 2},
+		{"Compare prices", true, 3},
+		{"Book hotel", false, 2},
+		{"Buy milk", false, 0},
+		{"Semi-skimmed", false, 6},
+	}
+	for index, want := range want {
+		task, err := app.store.Get(index + 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parent := 0
+		if task.ParentId != nil {
+			parent = *task.ParentId
+		}
+		if task.Title != want.title || task.Completed != want.completed || parent != want.parent {
+			t.Errorf("task %d is %+v, want %+v", index+2, task, want)
+		}
+		if !reflect.DeepEqual(res.Tasks[index], task) {
+			t.Errorf("response has %+v, stored %+v", res.Tasks[index], task)
+		}
+	}
+}
+
+func TestImportMarkdownRejectsInvalidItems(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	expectStatus(t, do(app, "POST", "/tasks/import/markdown", "just some notes\n"), http.StatusBadRequest)
+	expectStatus(t, do(app, "POST", "/tasks/import/markdown", "- [ ] Fine\n  - [ ]  \n"), http.StatusBadRequest)
+
+	tasks, err := app.store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("store has %d tasks after failed imports, want none", len(tasks))
+	}
+}
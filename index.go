@@ -0,0 +1,52 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// taskIndex holds every task in memory, keyed by ID, so FileStore reads
+// don't touch the disk.
+type taskIndex struct {
+	mu    sync.RWMutex
+	tasks map[int]Task
+}
+
+func newTaskIndex() *taskIndex {
+	return &taskIndex{tasks: map[int]Task{}}
+}
+
+func (index *taskIndex) get(taskId int) (Task, bool) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	task, ok := index.tasks[taskId]
+	return task, ok
+}
+
+// ids returns every indexed task ID in ascending order.
+func (index *taskIndex) ids() []int {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	ids := make([]int, 0, len(index.tasks))
+	for taskId := range index.tasks {
+		ids = append(ids, taskId)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (index *taskIndex) put(task Task) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	index.tasks[task.Id] = task
+}
+
+func (index *taskIndex) remove(taskId int) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	delete(index.tasks, taskId)
+}
@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type malformedRequest struct {
@@ -20,6 +22,40 @@ func (mr *malformedRequest) Error() string {
 	return mr.msg
 }
 
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSONError replies to the request with msg as a JSON error body,
+// like http.Error does for plain text.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: msg})
+}
+
+type referencedResponse struct {
+	Error        string `json:"error"`
+	ReferencedBy []int  `json:"referenced_by"`
+}
+
+// writeReferencedError is writeJSONError for a 409 refusing to delete a task
+// that other tasks refer to, listing them so clients can show what's in
+// the way.
+func writeReferencedError(w http.ResponseWriter, taskId int, referencedBy []int) {
+	ids := make([]string, len(referencedBy))
+	for index, id := range referencedBy {
+		ids[index] = strconv.Itoa(id)
+	}
+	msg := fmt.Sprintf("Task with ID %v is the parent of tasks %s, delete with ?force=true to move them up a level", taskId, strings.Join(ids, ", "))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(referencedResponse{Error: msg, ReferencedBy: referencedBy})
+}
+
 func decodeJsonBody(w http.ResponseWriter, r *http.Request, dst interface{}) error {
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
@@ -28,6 +64,7 @@ func decodeJsonBody(w http.ResponseWriter, r *http.Request, dst interface{}) err
 	if err != nil {
 		var syntaxError *json.SyntaxError
 		var unmarshalTypeError *json.UnmarshalTypeError
+		var timeParseError *time.ParseError
 
 		switch {
 		case errors.As(err, &syntaxError):
@@ -38,6 +75,10 @@ func decodeJsonBody(w http.ResponseWriter, r *http.Request, dst interface{}) err
 			msg := fmt.Sprintf("Request body contains an invalid value for the %q field (at position %d)", unmarshalTypeError.Field, unmarshalTypeError.Offset)
 			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
 
+		case errors.As(err, &timeParseError):
+			msg := fmt.Sprintf("Request body contains an invalid timestamp %q, expected RFC 3339 like 2006-01-02T15:04:05Z", timeParseError.Value)
+			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+
 		case strings.HasPrefix(err.Error(), "json: unknown field "):
 			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
 			msg := fmt.Sprintf("Request body contains unknown field %s", fieldName)
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// A limit caps how many of something a write may leave behind. Going over
+// soft still allows the write but logs and adds an X-Brain-Warning header
+// to the response; going over hard rejects it with status. Zero disables
+// either bound.
+type limit struct {
+	name   string
+	soft   int
+	hard   int
+	status int
+}
+
+func (l limit) check(w http.ResponseWriter, n int) error {
+	if l.hard > 0 && n > l.hard {
+		msg := fmt.Sprintf("%s would be %d, over the limit of %d", l.name, n, l.hard)
+		return &malformedRequest{status: l.status, msg: msg}
+	}
+	if l.soft > 0 && n > l.soft {
+		msg := fmt.Sprintf("%s is %d, over the soft limit of %d", l.name, n, l.soft)
+		log.Print(msg)
+		w.Header().Add("X-Brain-Warning", msg)
+	}
+	return nil
+}
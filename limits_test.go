@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTrashedTasksFreeTheirSlot(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.taskLimit.hard = 1
+
+	createTask(t, app, `{"Title":"Water plants"}`)
+	expectStatus(t, do(app, "POST", "/tasks", `{"Title":"Feed cat"}`), http.StatusConflict)
+
+	expectStatus(t, do(app, "DELETE", "/tasks/1", ""), http.StatusNoContent)
+	createTask(t, app, `{"Title":"Feed cat"}`)
+
+	// Restoring task 1 would make two live tasks again.
+	expectStatus(t, do(app, "POST", "/tasks/1/restore", ""), http.StatusConflict)
+}
+
+func TestTaskCountLimits(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.taskLimit.soft = 1
+	app.taskLimit.hard = 3
+
+	rec := do(app, "POST", "/tasks", `{"Title":"Water plants"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	if warning := rec.Header().Get("X-Brain-Warning"); warning != "" {
+		t.Errorf("got warning %q at the soft limit", warning)
+	}
+
+	rec = do(app, "POST", "/tasks", `{"Title":"Feed cat"}`)
+	expectStatus(t, rec, http.StatusCreated)
+	if warning, want := rec.Header().Get("X-Brain-Warning"), "Task count is 2, over the soft limit of 1"; warning != want {
+		t.Errorf("got warning %q, want %q", warning, want)
+	}
+
+	// A batch is rejected as a whole if it would go past the hard limit.
+	rec = do(app, "POST", "/tasks", `[{"Title":"Walk dog"},{"Title":"Wash car"}]`)
+	expectStatus(t, rec, http.StatusConflict)
+	var res errorResponse
+	decode(t, rec, &res)
+	if want := "Task count would be 4, over the limit of 3"; res.Error != want {
+		t.Errorf("got error %q, want %q", res.Error, want)
+	}
+	if got := listIds(t, app, "/tasks"); len(got) != 2 {
+		t.Errorf("a rejected batch left tasks %v", got)
+	}
+}
+
+func TestTagCountLimits(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.tagLimit.soft = 1
+	app.tagLimit.hard = 2
+
+	rec := do(app, "POST", "/tasks", `{"Title":"Water plants","Tags":["home","garden"]}`)
+	expectStatus(t, rec, http.StatusCreated)
+	if warning, want := rec.Header().Get("X-Brain-Warning"), "Tag count is 2, over the soft limit of 1"; warning != want {
+		t.Errorf("got warning %q, want %q", warning, want)
+	}
+
+	// Duplicates are normalized away before counting.
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"Tags":["home","Home","garden"]}`), http.StatusOK)
+
+	rec = do(app, "PATCH", "/tasks/1", `{"Tags":["home","garden","weekly"]}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+	var task Task
+	decode(t, do(app, "GET", "/tasks/1", ""), &task)
+	if len(task.Tags) != 2 {
+		t.Errorf("a rejected edit left tags %q", task.Tags)
+	}
+}
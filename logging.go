@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+)
+
+// logRequests logs the method, path, status, response size and duration
+// of every request once it has been served. It wraps everything else,
+// so requests rejected by basic auth are logged too. With
+// BRAIN_LOG_SAMPLE below 1 only that fraction of successful requests is
+// logged, picked at random, while errors are always logged.
+func (app *application) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := app.clock.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		if sw.status < http.StatusBadRequest && rand.Float64() >= app.logSample {
+			return
+		}
+		log.Printf("method=%s path=%q status=%d size=%d duration=%s",
+			r.Method, r.URL.Path, sw.status, sw.size, app.clock.Now().Sub(start))
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogRequestsTimesWithClock(t *testing.T) {
+	app, clock := newTestApp(t)
+	logs := captureLogs(t)
+
+	handler := app.logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clock.Advance(1500 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("queued"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/tasks", nil))
+
+	want := `method=POST path="/tasks" status=202 size=6 duration=1.5s`
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("logged %q, want it to contain %q", logs.String(), want)
+	}
+}
+
+func TestLogSampleAlwaysLogsErrors(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.logSample = 0
+	logs := captureLogs(t)
+
+	expectStatus(t, do(app, "GET", "/tasks", ""), http.StatusOK)
+	if strings.Contains(logs.String(), "status=200") {
+		t.Errorf("logged a successful request with BRAIN_LOG_SAMPLE=0: %q", logs.String())
+	}
+
+	expectStatus(t, do(app, "GET", "/tasks/nope", ""), http.StatusBadRequest)
+	expectStatus(t, serve(app, httptest.NewRequest("GET", "/tasks", nil)), http.StatusUnauthorized)
+	for _, want := range []string{`path="/tasks/nope" status=400`, `path="/tasks" status=401`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs %q are missing %q", logs.String(), want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 1000
+)
+
+// taskSorters orders tasks by the keys accepted in ?sort=. Prefixing a key
+// with "-" reverses the order.
+var taskSorters = map[string]func(a, b Task) int{
+	"id": func(a, b Task) int {
+		return cmp.Compare(a.Id, b.Id)
+	},
+	"title": func(a, b Task) int {
+		return cmp.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	},
+	"completed": func(a, b Task) int {
+		return cmp.Compare(boolRank(a.Completed), boolRank(b.Completed))
+	},
+	// priority puts high before low; -priority puts low first.
+	"priority": func(a, b Task) int {
+		return cmp.Compare(priorityRank(b.Priority), priorityRank(a.Priority))
+	},
+	"created": func(a, b Task) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	},
+	"updated": func(a, b Task) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	},
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type listOptions struct {
+	limit  int
+	offset int
+	sort   string
+}
+
+func parseListOptions(queryParams url.Values) (listOptions, error) {
+	opts := listOptions{limit: defaultListLimit, sort: "id"}
+
+	if value := queryParams.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			msg := fmt.Sprintf("Invalid limit %q, must be an integer from 1 to %d", value, maxListLimit)
+			return opts, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+		opts.limit = limit
+	}
+
+	if value := queryParams.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			msg := fmt.Sprintf("Invalid offset %q, must be a non-negative integer", value)
+			return opts, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+		opts.offset = offset
+	}
+
+	if value := queryParams.Get("sort"); value != "" {
+		if _, ok := taskSorters[strings.TrimPrefix(value, "-")]; !ok {
+			keys := make([]string, 0, len(taskSorters))
+			for key := range taskSorters {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			msg := fmt.Sprintf("Invalid sort %q, must be one of %s, optionally prefixed with -", value, strings.Join(keys, ", "))
+			return opts, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+		opts.sort = value
+	}
+
+	return opts, nil
+}
+
+// page sorts tasks in place and returns the requested window of them.
+func (opts listOptions) page(tasks []Task) []Task {
+	key, descending := strings.CutPrefix(opts.sort, "-")
+	sorter := taskSorters[key]
+	slices.SortStableFunc(tasks, func(a, b Task) int {
+		if descending {
+			return sorter(b, a)
+		}
+		return sorter(a, b)
+	})
+
+	if opts.offset >= len(tasks) {
+		return tasks[:0]
+	}
+	end := min(opts.offset+opts.limit, len(tasks))
+	return tasks[opts.offset:end]
+}
+
+// contentRange describes a page of count tasks starting at offset out of
+// total, like "tasks 0-49/327", for grid components that page with
+// Content-Range. An empty page has no range to give: "tasks */327".
+func contentRange(offset, count, total int) string {
+	if count == 0 {
+		return fmt.Sprintf("tasks */%d", total)
+	}
+	return fmt.Sprintf("tasks %d-%d/%d", offset, offset+count-1, total)
+}
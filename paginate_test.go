@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestListContentRange(t *testing.T) {
+	app, _ := newTestApp(t)
+	for index := 1; index <= 7; index++ {
+		createTask(t, app, fmt.Sprintf(`{"Title":"Task %d"}`, index))
+	}
+
+	for _, test := range []struct {
+		query string
+		want  string
+	}{
+		{"limit=3", "tasks 0-2/7"},
+		{"limit=3&offset=3", "tasks 3-5/7"},
+		{"limit=3&offset=6", "tasks 6-6/7"},
+		{"limit=3&offset=9", "tasks */7"},
+	} {
+		rec := do(app, "GET", "/tasks?"+test.query, "")
+		expectStatus(t, rec, http.StatusOK)
+		if got := rec.Header().Get("Content-Range"); got != test.want {
+			t.Errorf("?%s sent Content-Range %q, want %q", test.query, got, test.want)
+		}
+	}
+}
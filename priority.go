@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// priorities lists the priorities a task may have, from lowest to highest.
+var priorities = []string{"low", "medium", "high"}
+
+const defaultPriority = "medium"
+
+// normalizePriority lowercases a priority and checks it is one of
+// priorities. The empty string means the default priority.
+func normalizePriority(priority string) (string, error) {
+	priority = strings.ToLower(strings.TrimSpace(priority))
+	if priority == "" {
+		return defaultPriority, nil
+	}
+	if slices.Contains(priorities, priority) {
+		return priority, nil
+	}
+
+	msg := fmt.Sprintf("Invalid priority %q, must be one of %s", priority, strings.Join(priorities, ", "))
+	return "", &malformedRequest{status: http.StatusBadRequest, msg: msg}
+}
+
+func priorityRank(priority string) int {
+	return slices.Index(priorities, priority)
+}
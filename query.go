@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// taskQuery holds the filters shared by every endpoint that selects a
+// subset of tasks from query parameters.
+type taskQuery struct {
+	search string
+	fold   bool
+	color  string
+	filter taskPredicate
+
+	// priority, when set, keeps only tasks with that priority.
+	priority string
+
+	// completed, when set, keeps only tasks whose Completed matches it.
+	completed *bool
+
+	// availableAt, when set, hides tasks whose start date is after it.
+	availableAt *time.Time
+
+	// dueBefore and dueAfter, when set, keep only tasks with a due date
+	// strictly before or after them. Tasks without a due date never match.
+	dueBefore *time.Time
+	dueAfter  *time.Time
+
+	// dueOn, when set, keeps only tasks due during that calendar day, from
+	// its first instant up to the start of the next.
+	dueOn *[2]time.Time
+
+	// tags keeps only tasks carrying all of them, while untagged keeps only
+	// tasks with none.
+	tags     []string
+	untagged bool
+
+	// topLevel keeps only tasks without a parent.
+	topLevel bool
+
+	// includeDeleted keeps trashed tasks, which are otherwise left out.
+	includeDeleted bool
+
+	// sessionTouched, when non-nil, restricts results to these IDs.
+	sessionTouched map[int]bool
+}
+
+func (app *application) parseTaskQuery(r *http.Request) (taskQuery, error) {
+	queryParams := r.URL.Query()
+
+	var query taskQuery
+	query.search = queryParams.Get("q")
+
+	// The search ignores case. fold=true makes it ignore accents as well,
+	// so "cafe" finds "Café".
+	query.fold = queryParams.Get("fold") == "true"
+	if query.fold {
+		query.search = foldText(query.search)
+	} else {
+		query.search = foldCase(query.search)
+	}
+
+	if color := queryParams.Get("color"); color != "" {
+		color, err := normalizeColor(color)
+		if err != nil {
+			return query, err
+		}
+		query.color = color
+	}
+
+	if priority := queryParams.Get("priority"); priority != "" {
+		priority, err := normalizePriority(priority)
+		if err != nil {
+			return query, err
+		}
+		query.priority = priority
+	}
+
+	switch value := queryParams.Get("completed"); value {
+	case "":
+	case "true", "false":
+		completed := value == "true"
+		query.completed = &completed
+	default:
+		msg := fmt.Sprintf("Invalid completed %q, must be true or false", value)
+		return query, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	if expr := queryParams.Get("filter"); expr != "" {
+		filter, err := parseFilter(expr)
+		if err != nil {
+			return query, err
+		}
+		query.filter = filter
+	}
+
+	if queryParams.Get("available") == "true" {
+		now := app.clock.Now()
+		query.availableAt = &now
+	}
+
+	query.tags = normalizeTags(queryParams["tag"])
+	query.untagged = queryParams.Get("untagged") == "true"
+	query.includeDeleted = queryParams.Get("include_deleted") == "true"
+
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+	}{{"due_before", &query.dueBefore}, {"due_after", &query.dueAfter}} {
+		value := queryParams.Get(param.name)
+		if value == "" {
+			continue
+		}
+		due, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			msg := fmt.Sprintf("Invalid %s %q, expected RFC 3339 like 2006-01-02T15:04:05Z", param.name, value)
+			return query, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+		*param.dst = &due
+	}
+
+	// The day is in the server's timezone. Midnight comes from time.Date
+	// rather than adding 24 hours, since DST makes some days shorter or
+	// longer.
+	if value := queryParams.Get("due_on"); value != "" {
+		day, err := time.ParseInLocation(time.DateOnly, value, app.location)
+		if err != nil {
+			msg := fmt.Sprintf("Invalid due_on %q, expected a date like 2006-01-02", value)
+			return query, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+		query.dueOn = &[2]time.Time{day, day.AddDate(0, 0, 1)}
+	}
+
+	if queryParams.Get("session_changed") == "true" {
+		query.sessionTouched = app.sessions.touchedIds(sessionId(r), app.clock.Now())
+	}
+
+	return query, nil
+}
+
+func (query taskQuery) matches(task Task) bool {
+	if task.DeletedAt != nil && !query.includeDeleted {
+		return false
+	}
+	if query.topLevel && task.ParentId != nil {
+		return false
+	}
+	if query.sessionTouched != nil && !query.sessionTouched[task.Id] {
+		return false
+	}
+	title := foldCase(task.Title)
+	if query.fold {
+		title = foldText(task.Title)
+	}
+	if !strings.Contains(title, query.search) {
+		return false
+	}
+	if query.color != "" && task.Color != query.color {
+		return false
+	}
+	if query.priority != "" && task.Priority != query.priority {
+		return false
+	}
+	if query.completed != nil && task.Completed != *query.completed {
+		return false
+	}
+	if query.availableAt != nil && task.StartDate != nil && task.StartDate.After(*query.availableAt) {
+		return false
+	}
+	if query.dueBefore != nil && (task.Due == nil || !task.Due.Before(*query.dueBefore)) {
+		return false
+	}
+	if query.dueAfter != nil && (task.Due == nil || !task.Due.After(*query.dueAfter)) {
+		return false
+	}
+	if query.dueOn != nil && (task.Due == nil || task.Due.Before(query.dueOn[0]) || !task.Due.Before(query.dueOn[1])) {
+		return false
+	}
+	if !hasTags(task, query.tags) {
+		return false
+	}
+	if query.untagged && len(task.Tags) > 0 {
+		return false
+	}
+	if query.filter != nil && !query.filter(task) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+// listIds returns the IDs of the tasks GET target lists.
+func listIds(t *testing.T, app *application, target string) []int {
+	t.Helper()
+
+	rec := do(app, "GET", target, "")
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []Task
+	decode(t, rec, &tasks)
+	ids := []int{}
+	for _, task := range tasks {
+		ids = append(ids, task.Id)
+	}
+	return ids
+}
+
+func TestListDueOn(t *testing.T) {
+	app, _ := newTestApp(t)
+	for _, body := range []string{
+		`{"Title":"Before","Due":"2024-03-08T23:59:59Z"}`,
+		`{"Title":"Start","Due":"2024-03-09T00:00:00Z"}`,
+		`{"Title":"End","Due":"2024-03-09T23:59:59Z"}`,
+		`{"Title":"After","Due":"2024-03-10T00:00:00Z"}`,
+		`{"Title":"Offset","Due":"2024-03-09T20:00:00-05:00"}`,
+		`{"Title":"Undated"}`,
+	} {
+		createTask(t, app, body)
+	}
+
+	if got, want := listIds(t, app, "/tasks?due_on=2024-03-09"), []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	expectStatus(t, do(app, "GET", "/tasks?due_on=03/09/2024", ""), http.StatusBadRequest)
+}
+
+func TestListDueOnAcrossDST(t *testing.T) {
+	app, _ := newTestApp(t)
+	var err error
+	app.location, err = time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clocks in New York go from 02:00 to 03:00 on 10 March 2024, so that
+	// day is 23 hours long and ends at 04:00 UTC rather than 05:00.
+	for _, body := range []string{
+		`{"Title":"Start","Due":"2024-03-10T05:00:00Z"}`,
+		`{"Title":"End","Due":"2024-03-11T03:59:59Z"}`,
+		`{"Title":"Next day","Due":"2024-03-11T04:00:00Z"}`,
+		`{"Title":"Day before","Due":"2024-03-10T04:59:59Z"}`,
+	} {
+		createTask(t, app, body)
+	}
+
+	if got, want := listIds(t, app, "/tasks?due_on=2024-03-10"), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("due_on=2024-03-10: got %v, want %v", got, want)
+	}
+	if got, want := listIds(t, app, "/tasks?due_on=2024-03-11"), []int{3}; !slices.Equal(got, want) {
+		t.Errorf("due_on=2024-03-11: got %v, want %v", got, want)
+	}
+}
+
+func TestListAvailable(t *testing.T) {
+	app, clock := newTestApp(t)
+	createTask(t, app, `{"Title":"Now"}`)
+	createTask(t, app, `{"Title":"Started","StartDate":"2024-03-01T00:00:00Z"}`)
+	createTask(t, app, `{"Title":"Someday","StartDate":"2024-03-06T09:00:00Z"}`)
+
+	if got, want := listIds(t, app, "/tasks?available=true"), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("available %v, want %v", got, want)
+	}
+	if got, want := listIds(t, app, "/tasks"), []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("listed %v without available, want %v", got, want)
+	}
+
+	// A task becomes available the moment its start date arrives.
+	clock.Set(time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC))
+	if got, want := listIds(t, app, "/tasks?available=true"), []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("available %v once started, want %v", got, want)
+	}
+}
+
+func TestListUntagged(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"No tags"}`)
+	createTask(t, app, `{"Title":"Null tags","Tags":null}`)
+	createTask(t, app, `{"Title":"Tagged","Tags":["home"]}`)
+	empty := createTask(t, app, `{"Title":"Empty tags"}`)
+
+	// Files written by hand or by older versions may hold an empty list.
+	empty.Tags = []string{}
+	err := app.store.Update(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := listIds(t, app, "/tasks?untagged=true"), []int{1, 2, 4}; !slices.Equal(got, want) {
+		t.Errorf("untagged %v, want %v", got, want)
+	}
+	if got := listIds(t, app, "/tasks?untagged=true&tag=home"); len(got) != 0 {
+		t.Errorf("untagged with a tag listed %v, want nothing", got)
+	}
+
+	// Removing a task's last tag makes it untagged.
+	expectStatus(t, do(app, "PATCH", "/tasks/3", `{"Tags":[]}`), http.StatusOK)
+	if got, want := listIds(t, app, "/tasks?untagged=true"), []int{1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("untagged %v after clearing tags, want %v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// maxLimitedIPs bounds the memory used by rateLimiter, like maxWatchedIPs
+// does for rateWatcher.
+const maxLimitedIPs = 10000
+
+type ipBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter gives each client IP a token bucket that refills at limit
+// tokens per second up to burst, and rejects requests once it's empty.
+type rateLimiter struct {
+	mu    sync.Mutex
+	limit rate.Limit
+	burst int
+	ips   map[string]*ipBucket
+}
+
+func newRateLimiter(limit rate.Limit, burst int) *rateLimiter {
+	return &rateLimiter{
+		limit: limit,
+		burst: burst,
+		ips:   make(map[string]*ipBucket),
+	}
+}
+
+// allow takes a token from ip's bucket, or reports how long until one is
+// available.
+func (rl *rateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	bucket, ok := rl.ips[ip]
+	if !ok {
+		if len(rl.ips) >= maxLimitedIPs {
+			rl.expire(now)
+			if len(rl.ips) >= maxLimitedIPs {
+				return true, 0
+			}
+		}
+		bucket = &ipBucket{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.ips[ip] = bucket
+	}
+	bucket.lastSeen = now
+
+	reservation := bucket.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// expire drops the buckets that have refilled completely, since a new
+// bucket would be identical. Callers must hold rl.mu.
+func (rl *rateLimiter) expire(now time.Time) {
+	refill := time.Duration(float64(rl.burst) / float64(rl.limit) * float64(time.Second))
+	for ip, bucket := range rl.ips {
+		if now.Sub(bucket.lastSeen) >= refill {
+			delete(rl.ips, ip)
+		}
+	}
+}
+
+// limitRequests answers 429 Too Many Requests, with a Retry-After in
+// whole seconds, to clients that have used up their bucket. It runs
+// before basic auth so password guessing is throttled too.
+func (app *application) limitRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.rateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		ok, retryAfter := app.rateLimiter.allow(ip, app.clock.Now())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeJSONError(w, http.StatusTooManyRequests, "Too many requests, slow down")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
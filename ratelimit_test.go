@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	app, clock := newTestApp(t)
+	app.rateLimiter = newRateLimiter(0.5, 2)
+
+	get := func(ip string) int {
+		req := newRequest("GET", "/tasks", "")
+		req.RemoteAddr = ip + ":1234"
+		rec := serve(app, req)
+		if rec.Code == http.StatusTooManyRequests {
+			if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "2" {
+				t.Errorf("got Retry-After %q, want 2", retryAfter)
+			}
+		}
+		return rec.Code
+	}
+
+	// The burst goes through at once, then the bucket is empty.
+	for index := 0; index < 2; index++ {
+		if status := get("192.0.2.1"); status != http.StatusOK {
+			t.Fatalf("request %d of the burst got status %d", index+1, status)
+		}
+	}
+	if status := get("192.0.2.1"); status != http.StatusTooManyRequests {
+		t.Fatalf("request past the burst got status %d, want 429", status)
+	}
+
+	// Other clients have buckets of their own.
+	if status := get("192.0.2.2"); status != http.StatusOK {
+		t.Errorf("another IP got status %d", status)
+	}
+
+	// Requests no faster than the refill rate keep getting through.
+	for index := 0; index < 10; index++ {
+		clock.Advance(2 * time.Second)
+		if status := get("192.0.2.1"); status != http.StatusOK {
+			t.Fatalf("trickled request %d got status %d", index+1, status)
+		}
+	}
+}
+
+func TestRateLimitExpiresFullBuckets(t *testing.T) {
+	rl := newRateLimiter(1, 5)
+	now := testNow
+	rl.allow("192.0.2.1", now)
+	rl.allow("192.0.2.2", now.Add(4*time.Second))
+
+	rl.mu.Lock()
+	rl.expire(now.Add(5 * time.Second))
+	_, kept := rl.ips["192.0.2.2"]
+	count := len(rl.ips)
+	rl.mu.Unlock()
+	if count != 1 || !kept {
+		t.Errorf("kept %d buckets, want only the one still refilling", count)
+	}
+}
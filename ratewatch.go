@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxWatchedIPs bounds the memory used by rateWatcher. Once this many
+// addresses are tracked, new addresses are ignored until old windows
+// expire.
+const maxWatchedIPs = 10000
+
+type ipWindow struct {
+	start  time.Time
+	count  int
+	warned bool
+}
+
+// rateWatcher counts requests per client IP over a fixed window and logs
+// a warning the first time an address goes over the threshold within a
+// window. It never blocks requests.
+type rateWatcher struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	ips       map[string]*ipWindow
+}
+
+func newRateWatcher(threshold int, window time.Duration) *rateWatcher {
+	return &rateWatcher{
+		threshold: threshold,
+		window:    window,
+		ips:       make(map[string]*ipWindow),
+	}
+}
+
+func (rw *rateWatcher) record(ip string, now time.Time) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	win, ok := rw.ips[ip]
+	if !ok || now.Sub(win.start) >= rw.window {
+		if !ok && len(rw.ips) >= maxWatchedIPs {
+			rw.expire(now)
+			if len(rw.ips) >= maxWatchedIPs {
+				return
+			}
+		}
+		win = &ipWindow{start: now}
+		rw.ips[ip] = win
+	}
+
+	win.count++
+	if win.count > rw.threshold && !win.warned {
+		win.warned = true
+		log.Printf("warning: %s made more than %d requests in %s", ip, rw.threshold, rw.window)
+	}
+}
+
+// expire drops every window that has ended. Callers must hold rw.mu.
+func (rw *rateWatcher) expire(now time.Time) {
+	for ip, win := range rw.ips {
+		if now.Sub(win.start) >= rw.window {
+			delete(rw.ips, ip)
+		}
+	}
+}
+
+func (app *application) watchRequestRate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+		app.rateWatcher.record(ip, app.clock.Now())
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateWatcherWarnsOnce(t *testing.T) {
+	logs := captureLogs(t)
+	rw := newRateWatcher(3, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		rw.record("192.0.2.1", testNow)
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("warned at the threshold: %q", logs.String())
+	}
+
+	rw.record("192.0.2.1", testNow)
+	rw.record("192.0.2.1", testNow)
+	rw.record("192.0.2.2", testNow)
+	if got := strings.Count(logs.String(), "192.0.2.1 made more than 3 requests"); got != 1 {
+		t.Errorf("warned %d times, want once: %q", got, logs.String())
+	}
+	if strings.Contains(logs.String(), "192.0.2.2") {
+		t.Errorf("warned about another address: %q", logs.String())
+	}
+
+	// A new window starts the count again.
+	logs.Reset()
+	later := testNow.Add(time.Minute)
+	for i := 0; i < 4; i++ {
+		rw.record("192.0.2.1", later)
+	}
+	if !strings.Contains(logs.String(), "192.0.2.1") {
+		t.Errorf("didn't warn in the next window: %q", logs.String())
+	}
+}
+
+func TestWatchRequestRate(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.rateWatcher = newRateWatcher(2, time.Minute)
+	logs := captureLogs(t)
+
+	for i := 0; i < 3; i++ {
+		do(app, "GET", "/tasks", "")
+	}
+	if !strings.Contains(logs.String(), "made more than 2 requests") {
+		t.Errorf("logs %q have no warning", logs.String())
+	}
+}
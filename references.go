@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// taskReference is a task that refers to another, and how. Subtasks refer
+// to their parent, and are the only references so far.
+type taskReference struct {
+	Task
+	Reference string `json:"reference"`
+}
+
+// references lists the tasks outside the trash that refer to taskId, so
+// clients can show what deleting it would affect.
+func (app *application) references(w http.ResponseWriter, r *http.Request, taskId int) {
+	_, err := app.readTask(taskId)
+	if errors.Is(err, os.ErrNotExist) {
+		msg := fmt.Sprintf("Task with ID %v not found", taskId)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving references, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	children, err := app.children(taskId)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving references, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	references := []taskReference{}
+	for _, child := range children {
+		if child.DeletedAt == nil {
+			references = append(references, taskReference{Task: child, Reference: "parent"})
+		}
+	}
+
+	referencesJson, err := json.Marshal(references)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving references, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(referencesJson)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Trip"}`)
+	createTask(t, app, `{"Title":"Pack","ParentId":1}`)
+	createTask(t, app, `{"Title":"Book hotel","ParentId":1}`)
+	createTask(t, app, `{"Title":"Buy towel","ParentId":2}`)
+	createTask(t, app, `{"Title":"Cancel flight","ParentId":1}`)
+	expectStatus(t, do(app, "DELETE", "/tasks/5", ""), http.StatusNoContent)
+
+	// Only direct references count, and trashed tasks don't.
+	rec := do(app, "GET", "/tasks/1/references", "")
+	expectStatus(t, rec, http.StatusOK)
+	var references []taskReference
+	decode(t, rec, &references)
+	if len(references) != 2 || references[0].Id != 2 || references[1].Id != 3 {
+		t.Fatalf("got references %+v, want tasks 2 and 3", references)
+	}
+	for _, reference := range references {
+		if reference.Reference != "parent" {
+			t.Errorf("task %d refers as %q, want parent", reference.Id, reference.Reference)
+		}
+	}
+
+	rec = do(app, "GET", "/tasks/4/references", "")
+	expectStatus(t, rec, http.StatusOK)
+	if body := rec.Body.String(); body != "[]" {
+		t.Errorf("unreferenced task got %s, want []", body)
+	}
+
+	expectStatus(t, do(app, "GET", "/tasks/9/references", ""), http.StatusNotFound)
+	expectStatus(t, do(app, "POST", "/tasks/1/references", ""), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const maxRestoreBytes = 64 << 20
+
+// archiveTasksDir is the directory task files may be nested under inside
+// a backup archive. It is fixed, whatever TASKS_DIR is set to locally.
+const archiveTasksDir = "tasks"
+
+type restoreResponse struct {
+	Restored int `json:"restored"`
+	Removed  int `json:"removed"`
+}
+
+// restore installs tasks from an uploaded tar, tar.gz or zip backup.
+// Every entry is validated before anything is written, so a bad archive
+// leaves the store untouched. With ?mode=replace, tasks that are
+// not in the archive are deleted; the default ?mode=merge keeps them.
+func (app *application) restore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		msg := fmt.Sprintf("Unsupported request method %v to /admin/restore", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "merge"
+	}
+	if mode != "merge" && mode != "replace" {
+		msg := fmt.Sprintf("Invalid restore mode %q, must be merge or replace", mode)
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	archive, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRestoreBytes))
+	if err != nil {
+		msg := fmt.Sprintf("Could not read backup archive, %q", err.Error())
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	restored, err := readBackup(archive)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			msg := fmt.Sprintf("Could not read backup archive, %q", err.Error())
+			writeJSONError(w, http.StatusBadRequest, msg)
+		}
+		return
+	}
+
+	if len(restored) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "Backup archive contains no tasks")
+		return
+	}
+
+	// Restored tasks go through the same title and tag checks as new ones,
+	// in ID order so the first bad task is the one reported.
+	restoredIds := make([]int, 0, len(restored))
+	for taskId := range restored {
+		restoredIds = append(restoredIds, taskId)
+	}
+	slices.Sort(restoredIds)
+	for _, taskId := range restoredIds {
+		task := restored[taskId]
+		err = app.validateTask(task)
+		if err == nil {
+			err = app.tagLimit.check(w, len(task.Tags))
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Backup task %v: %s", taskId, err.Error())
+			writeJSONError(w, http.StatusBadRequest, msg)
+			return
+		}
+	}
+
+	// Restoring overwrites and deletes existing tasks, so it holds updateMu
+	// as well as createMu, like create does.
+	app.createMu.Lock()
+	defer app.createMu.Unlock()
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
+
+	existing, err := app.store.List()
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while restoring tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	// The task count limit applies to the live tasks the restore adds, net
+	// of the ones it overwrites or, in replace mode, removes.
+	err = app.checkTaskCount(w, restoredLiveTasks(existing, restored, mode == "replace"))
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			msg := fmt.Sprintf("An error occurred while restoring tasks, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+		}
+		return
+	}
+
+	var res restoreResponse
+	if mode == "replace" {
+		for _, task := range existing {
+			if _, ok := restored[task.Id]; ok {
+				continue
+			}
+			err = app.store.Delete(task.Id)
+			if err != nil {
+				msg := fmt.Sprintf("An error occurred while restoring tasks, %q", err.Error())
+				writeJSONError(w, http.StatusInternalServerError, msg)
+				return
+			}
+			res.Removed++
+		}
+	}
+
+	for _, task := range restored {
+		err := app.store.Update(task)
+		if err != nil {
+			msg := fmt.Sprintf("An error occurred while restoring tasks, %q", err.Error())
+			writeJSONError(w, http.StatusInternalServerError, msg)
+			return
+		}
+		res.Restored++
+	}
+
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while restoring tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resJson)
+}
+
+// restoredLiveTasks returns how many more live tasks there are after
+// restoring tasks over existing, which is negative if there are fewer.
+// With replace, existing tasks that aren't restored are removed.
+func restoredLiveTasks(existing []Task, restored map[int]Task, replace bool) int {
+	added := 0
+	for _, task := range restored {
+		if task.DeletedAt == nil {
+			added++
+		}
+	}
+	for _, task := range existing {
+		if task.DeletedAt != nil {
+			continue
+		}
+		if _, ok := restored[task.Id]; ok || replace {
+			added--
+		}
+	}
+	return added
+}
+
+// readBackup extracts and validates every task in a backup archive,
+// detecting the format from its leading bytes.
+func readBackup(archive []byte) (map[int]Task, error) {
+	tasks := make(map[int]Task)
+	add := func(name string, contents []byte) error {
+		task, err := validateBackupEntry(name, contents)
+		if err != nil {
+			return err
+		}
+		if _, ok := tasks[task.Id]; ok {
+			msg := fmt.Sprintf("Backup contains task %v more than once", task.Id)
+			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+		tasks[task.Id] = task
+		return nil
+	}
+
+	// Entries share a budget of maxRestoreBytes, as the upload itself
+	// does, so a small compressed archive can't expand without bound.
+	budget := int64(maxRestoreBytes)
+	read := func(entry io.Reader) ([]byte, error) {
+		contents, err := io.ReadAll(io.LimitReader(entry, budget+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(contents)) > budget {
+			msg := fmt.Sprintf("Backup archive expands to more than %d bytes", maxRestoreBytes)
+			return nil, &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msg}
+		}
+		budget -= int64(len(contents))
+		return contents, nil
+	}
+
+	switch {
+	case bytes.HasPrefix(archive, []byte("PK\x03\x04")):
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range zr.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			contents, err := read(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			err = add(file.Name, contents)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		var archiveReader io.Reader = bytes.NewReader(archive)
+		if bytes.HasPrefix(archive, []byte{0x1f, 0x8b}) {
+			gz, err := gzip.NewReader(archiveReader)
+			if err != nil {
+				return nil, err
+			}
+			defer gz.Close()
+			archiveReader = gz
+		}
+
+		tr := tar.NewReader(archiveReader)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if header.Typeflag == tar.TypeDir {
+				continue
+			}
+			if header.Typeflag != tar.TypeReg {
+				msg := fmt.Sprintf("Backup entry %q is not a regular file", header.Name)
+				return nil, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+			}
+			contents, err := read(tr)
+			if err != nil {
+				return nil, err
+			}
+			err = add(header.Name, contents)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// validateBackupEntry accepts entries named <id>.json, optionally inside a
+// single tasks/ directory, whose contents are a task with the same ID.
+// Anything else, including absolute paths and ../ traversal, is rejected.
+func validateBackupEntry(name string, contents []byte) (Task, error) {
+	var task Task
+
+	invalid := func(reason string) error {
+		msg := fmt.Sprintf("Invalid backup entry %q: %s", name, reason)
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	if strings.HasPrefix(name, "/") || strings.Contains(name, "\\") || path.Clean(name) != name {
+		return task, invalid("path must be relative and clean")
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return task, invalid("path must not contain ..")
+		}
+	}
+
+	filename := strings.TrimPrefix(name, archiveTasksDir+"/")
+	if strings.Contains(filename, "/") || path.Ext(filename) != ".json" {
+		return task, invalid("expected <id>.json")
+	}
+	taskId, err := strconv.Atoi(strings.TrimSuffix(filename, ".json"))
+	if err != nil || taskId < 1 {
+		return task, invalid("expected <id>.json")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&task)
+	if err != nil {
+		return task, invalid(err.Error())
+	}
+	if task.Id != taskId {
+		return task, invalid(fmt.Sprintf("contains task %v", task.Id))
+	}
+
+	task.Color, err = normalizeColor(task.Color)
+	if err != nil {
+		return task, invalid(err.Error())
+	}
+	task.Priority, err = normalizePriority(task.Priority)
+	if err != nil {
+		return task, invalid(err.Error())
+	}
+	task.Tags = normalizeTags(task.Tags)
+
+	return task, nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tarArchive builds a tar archive holding files, a map of entry names to
+// contents.
+func tarArchive(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	for name, contents := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg})
+		if err == nil {
+			_, err = tw.Write([]byte(contents))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := tw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive.String()
+}
+
+func TestRestoreNormalizesTags(t *testing.T) {
+	app, _ := newTestApp(t)
+	archive := tarArchive(t, map[string]string{
+		"tasks/1.json": `{"Id":1,"Title":"Water plants","Tags":["Home"," home ","GARDEN"]}`,
+	})
+
+	expectStatus(t, do(app, "POST", "/admin/restore", archive), http.StatusOK)
+	task, err := app.store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(task.Tags, []string{"home", "garden"}) {
+		t.Errorf("restored tags %q, want [home garden]", task.Tags)
+	}
+}
+
+func TestRestoreValidatesTasks(t *testing.T) {
+	for name, test := range map[string]struct {
+		task string
+		want string
+	}{
+		"blank title":   {`{"Id":2,"Title":"  "}`, "Backup task 2: Title must not be empty"},
+		"too many tags": {`{"Id":2,"Title":"Feed cat","Tags":["a","b","c"]}`, "Backup task 2: Tag count would be 3, over the limit of 2"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			app.tagLimit.hard = 2
+			archive := tarArchive(t, map[string]string{
+				"1.json": `{"Id":1,"Title":"Water plants"}`,
+				"2.json": test.task,
+			})
+
+			rec := do(app, "POST", "/admin/restore", archive)
+			expectStatus(t, rec, http.StatusBadRequest)
+			if !strings.Contains(rec.Body.String(), test.want) {
+				t.Errorf("got %s, want the error %q", rec.Body.String(), test.want)
+			}
+			tasks, err := app.store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != 0 {
+				t.Errorf("a rejected restore stored %+v", tasks)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	for mode, want := range map[string]struct {
+		ids []int
+		res restoreResponse
+	}{
+		"merge":   {[]int{1, 2, 3}, restoreResponse{Restored: 2}},
+		"replace": {[]int{2, 3}, restoreResponse{Restored: 2, Removed: 1}},
+	} {
+		t.Run(mode, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			createTask(t, app, `{"Title":"Water plants"}`)
+			createTask(t, app, `{"Title":"Feed cat"}`)
+			archive := tarArchive(t, map[string]string{
+				"tasks/2.json": `{"Id":2,"Title":"Feed dog"}`,
+				"tasks/3.json": `{"Id":3,"Title":"Walk dog"}`,
+			})
+
+			rec := do(app, "POST", "/admin/restore?mode="+mode, archive)
+			expectStatus(t, rec, http.StatusOK)
+			var res restoreResponse
+			decode(t, rec, &res)
+			if res != want.res {
+				t.Errorf("got %+v, want %+v", res, want.res)
+			}
+			if got := listIds(t, app, "/tasks"); !slices.Equal(got, want.ids) {
+				t.Errorf("left %v, want %v", got, want.ids)
+			}
+			task, err := app.store.Get(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if task.Title != "Feed dog" {
+				t.Errorf("task 2 is %q, want the restored Feed dog", task.Title)
+			}
+		})
+	}
+}
+
+func TestRestoreRejectsUnsafeEntries(t *testing.T) {
+	for _, name := range []string{
+		"../1.json",
+		"tasks/../../1.json",
+		"/etc/1.json",
+		"./1.json",
+		`tasks\1.json`,
+		"other/1.json",
+		"tasks/one.json",
+	} {
+		t.Run(name, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			archive := tarArchive(t, map[string]string{
+				"tasks/2.json": `{"Id":2,"Title":"Feed cat"}`,
+				name:           `{"Id":1,"Title":"Water plants"}`,
+			})
+
+			rec := do(app, "POST", "/admin/restore", archive)
+			expectStatus(t, rec, http.StatusBadRequest)
+			if !strings.Contains(rec.Body.String(), "Invalid backup entry") {
+				t.Errorf("got %s, want an invalid entry error", rec.Body.String())
+			}
+			tasks, err := app.store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != 0 {
+				t.Errorf("a rejected restore stored %+v", tasks)
+			}
+		})
+	}
+}
+
+func TestRestoreRejectsLinks(t *testing.T) {
+	app, _ := newTestApp(t)
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	err := tw.WriteHeader(&tar.Header{Name: "1.json", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(app, "POST", "/admin/restore", archive.String())
+	expectStatus(t, rec, http.StatusBadRequest)
+	if !strings.Contains(rec.Body.String(), "is not a regular file") {
+		t.Errorf("got %s, want a not a regular file error", rec.Body.String())
+	}
+}
+
+func TestRestoreChecksTaskCount(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.taskLimit.hard = 2
+	createTask(t, app, `{"Title":"Water plants"}`)
+	createTask(t, app, `{"Title":"Feed cat"}`)
+
+	// Overwriting a task doesn't add one.
+	archive := tarArchive(t, map[string]string{"2.json": `{"Id":2,"Title":"Feed dog"}`})
+	expectStatus(t, do(app, "POST", "/admin/restore", archive), http.StatusOK)
+
+	archive = tarArchive(t, map[string]string{
+		"2.json": `{"Id":2,"Title":"Feed dog"}`,
+		"3.json": `{"Id":3,"Title":"Walk dog"}`,
+	})
+	rec := do(app, "POST", "/admin/restore", archive)
+	expectStatus(t, rec, http.StatusConflict)
+	var res errorResponse
+	decode(t, rec, &res)
+	if want := "Task count would be 3, over the limit of 2"; res.Error != want {
+		t.Errorf("got error %q, want %q", res.Error, want)
+	}
+	if got := listIds(t, app, "/tasks"); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("a rejected restore left %v", got)
+	}
+
+	// Replacing removes task 1, which makes room for task 3.
+	expectStatus(t, do(app, "POST", "/admin/restore?mode=replace", archive), http.StatusOK)
+	if got := listIds(t, app, "/tasks"); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("replacing left %v, want [2 3]", got)
+	}
+}
+
+func TestRestoreLimitsExpandedSize(t *testing.T) {
+	// Two entries, each padded past half the budget, compress to almost
+	// nothing but expand to more than maxRestoreBytes between them.
+	padded := func(taskId int) io.Reader {
+		task := strings.NewReader(fmt.Sprintf(`{"Id":%d,"Title":"Water plants"}`, taskId))
+		padding := io.LimitReader(spaces{}, maxRestoreBytes/2+1)
+		return io.MultiReader(task, padding)
+	}
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for taskId := 1; taskId <= 2; taskId++ {
+		entry, err := zw.Create(fmt.Sprintf("%d.json", taskId))
+		if err == nil {
+			_, err = io.Copy(entry, padded(taskId))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tarred bytes.Buffer
+	gz := gzip.NewWriter(&tarred)
+	tw := tar.NewWriter(gz)
+	for taskId := 1; taskId <= 2; taskId++ {
+		entry, err := io.ReadAll(padded(taskId))
+		if err == nil {
+			err = tw.WriteHeader(&tar.Header{Name: fmt.Sprintf("%d.json", taskId), Mode: 0644, Size: int64(len(entry)), Typeflag: tar.TypeReg})
+		}
+		if err == nil {
+			_, err = tw.Write(entry)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = tw.Close()
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, archive := range map[string]*bytes.Buffer{"zip": &zipped, "tar.gz": &tarred} {
+		t.Run(name, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			rec := do(app, "POST", "/admin/restore", archive.String())
+			expectStatus(t, rec, http.StatusRequestEntityTooLarge)
+			tasks, err := app.store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != 0 {
+				t.Errorf("a rejected restore stored %+v", tasks)
+			}
+		})
+	}
+}
+
+// spaces is an endless reader of spaces, which JSON allows after a value.
+type spaces struct{}
+
+func (spaces) Read(p []byte) (int, error) {
+	for index := range p {
+		p[index] = ' '
+	}
+	return len(p), nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type scheduleRequest struct {
+	Ids   []int
+	DueIn string `json:"due_in"`
+
+	// Shift moves each task's due date by DueIn instead of setting it from
+	// now. Tasks without a due date are scheduled from now either way.
+	Shift bool
+}
+
+// dueOffset is a due_in like "2d" or "90m". Days and weeks are calendar
+// days, so "1d" keeps the time of day across a DST change.
+type dueOffset struct {
+	days     int
+	duration time.Duration
+}
+
+func parseDueOffset(value string) (dueOffset, error) {
+	for suffix, days := range map[string]int{"d": 1, "w": 7} {
+		count, ok := strings.CutSuffix(value, suffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err == nil {
+			return dueOffset{days: n * days}, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		msg := fmt.Sprintf("Invalid due_in %q, expected a duration like 2d, 1w or 3h30m", value)
+		return dueOffset{}, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+	return dueOffset{duration: duration}, nil
+}
+
+func (offset dueOffset) from(t time.Time) time.Time {
+	return t.AddDate(0, 0, offset.days).Add(offset.duration)
+}
+
+// schedule sets the due date of several tasks at once, reporting the
+// outcome for each task rather than failing the whole batch.
+func (app *application) schedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/schedule", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	var req scheduleRequest
+	err := decodeJsonBody(w, r, &req)
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			writeJSONError(w, mr.status, mr.msg)
+		} else {
+			log.Print(err.Error())
+			writeJSONError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+		return
+	}
+	if len(req.Ids) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "Request body must list at least one task ID in ids")
+		return
+	}
+	offset, err := parseDueOffset(req.DueIn)
+	if err != nil {
+		var mr *malformedRequest
+		errors.As(err, &mr)
+		writeJSONError(w, mr.status, mr.msg)
+		return
+	}
+
+	app.updateMu.Lock()
+	defer app.updateMu.Unlock()
+
+	res := batchResponse{Results: []batchResult{}}
+	for _, taskId := range req.Ids {
+		result := batchResult{Id: taskId, Status: http.StatusOK}
+
+		task, err := app.readTask(taskId)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			result.Status = http.StatusNotFound
+			result.Error = fmt.Sprintf("Task with ID %v not found", taskId)
+		case err != nil:
+			result.Status = http.StatusInternalServerError
+			result.Error = fmt.Sprintf("An error occurred while retrieving your task, %q", err.Error())
+		case app.lockCompleted && task.Completed:
+			result.Status = http.StatusConflict
+			result.Error = fmt.Sprintf("Task with ID %v is completed and locked, un-complete it before editing", taskId)
+		}
+		if result.Error != "" {
+			res.Results = append(res.Results, result)
+			continue
+		}
+
+		now := app.clock.Now()
+		due := offset.from(now.In(app.location))
+		if req.Shift && task.Due != nil {
+			due = offset.from(task.Due.In(app.location))
+		}
+		task.Due = &due
+		task.UpdatedAt = now
+
+		err = app.store.Update(task)
+		if err != nil {
+			result.Status = http.StatusInternalServerError
+			result.Error = fmt.Sprintf("An error occurred while saving your task, %q", err.Error())
+			res.Results = append(res.Results, result)
+			continue
+		}
+		app.sessions.touch(sessionId(r), task.Id, now)
+
+		result.Task = &task
+		res.Results = append(res.Results, result)
+	}
+
+	writeBatchResponse(w, res)
+}
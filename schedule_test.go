@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	app, clock := newTestApp(t)
+	createTask(t, app, `{"Title":"Buy milk"}`)
+	createTask(t, app, `{"Title":"File taxes","Due":"2024-04-15T17:00:00Z"}`)
+	clock.Advance(time.Hour)
+
+	rec := do(app, "POST", "/tasks/schedule", `{"ids":[1,99,2],"due_in":"2d"}`)
+	expectStatus(t, rec, http.StatusMultiStatus)
+	var res batchResponse
+	decode(t, rec, &res)
+
+	want := clock.Now().AddDate(0, 0, 2)
+	if len(res.Results) != 3 {
+		t.Fatalf("got %d results, want 3: %+v", len(res.Results), res.Results)
+	}
+	for _, index := range []int{0, 2} {
+		result := res.Results[index]
+		if result.Status != http.StatusOK || result.Task == nil || result.Task.Due == nil || !result.Task.Due.Equal(want) {
+			t.Errorf("result %d is %+v, want due %v", index, result, want)
+		}
+	}
+	if missing := res.Results[1]; missing.Id != 99 || missing.Status != http.StatusNotFound || missing.Error == "" {
+		t.Errorf("missing task result is %+v, want a 404", missing)
+	}
+
+	stored, err := app.store.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Due == nil || !stored.Due.Equal(want) {
+		t.Errorf("stored due date is %v, want %v", stored.Due, want)
+	}
+}
+
+func TestScheduleShift(t *testing.T) {
+	app, clock := newTestApp(t)
+	createTask(t, app, `{"Title":"Buy milk"}`)
+	createTask(t, app, `{"Title":"File taxes","Due":"2024-04-15T17:00:00Z"}`)
+
+	rec := do(app, "POST", "/tasks/schedule", `{"ids":[1,2],"due_in":"1w","shift":true}`)
+	expectStatus(t, rec, http.StatusOK)
+	var res batchResponse
+	decode(t, rec, &res)
+
+	for index, want := range []time.Time{
+		clock.Now().AddDate(0, 0, 7),
+		time.Date(2024, time.April, 22, 17, 0, 0, 0, time.UTC),
+	} {
+		if due := res.Results[index].Task.Due; due == nil || !due.Equal(want) {
+			t.Errorf("task %d is due %v, want %v", index+1, due, want)
+		}
+	}
+}
+
+func TestScheduleValidatesDuration(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Buy milk"}`)
+
+	for _, body := range []string{
+		`{"ids":[1],"due_in":"soon"}`,
+		`{"ids":[1],"due_in":""}`,
+		`{"ids":[],"due_in":"2d"}`,
+	} {
+		expectStatus(t, do(app, "POST", "/tasks/schedule", body), http.StatusBadRequest)
+	}
+	expectStatus(t, do(app, "GET", "/tasks/schedule", ""), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+type fieldSchema struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Format   string   `json:"format,omitempty"`
+	Required bool     `json:"required"`
+	ReadOnly bool     `json:"readOnly,omitempty"`
+	Nullable bool     `json:"nullable,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+}
+
+// taskFieldRules declares what reflection can't tell us about Task fields.
+// Fields without an entry are optional and writable.
+var taskFieldRules = map[string]fieldSchema{
+	"Id":        {ReadOnly: true},
+	"Title":     {Required: true},
+	"Color":     {Enum: colorPalette, Pattern: hexColor.String()},
+	"Priority":  {Enum: priorities},
+	"Tags":      {Nullable: true},
+	"CreatedAt": {ReadOnly: true},
+	"UpdatedAt": {ReadOnly: true},
+	"DeletedAt": {ReadOnly: true},
+}
+
+// taskSchema describes every Task field, deriving names and types from the
+// struct so the schema can't drift from what the API actually accepts.
+func taskSchema() []fieldSchema {
+	fields := reflect.VisibleFields(reflect.TypeOf(Task{}))
+
+	schema := make([]fieldSchema, 0, len(fields))
+	for _, field := range fields {
+		fs := taskFieldRules[field.Name]
+		fs.Name = field.Name
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fs.Nullable = true
+			fieldType = fieldType.Elem()
+		}
+		fs.Type, fs.Format = jsonSchemaType(fieldType)
+
+		schema = append(schema, fs)
+	}
+
+	return schema
+}
+
+func jsonSchemaType(t reflect.Type) (string, string) {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string", "date-time"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer", ""
+	case reflect.Float32, reflect.Float64:
+		return "number", ""
+	case reflect.String:
+		return "string", ""
+	case reflect.Slice, reflect.Array:
+		return "array", ""
+	default:
+		return "object", ""
+	}
+}
+
+func schema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/schema", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	schemaJson, err := json.Marshal(map[string]interface{}{"fields": taskSchema()})
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while building the task schema, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(schemaJson)
+}
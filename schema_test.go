@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaListsTaskFields(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	rec := do(app, "GET", "/tasks/schema", "")
+	expectStatus(t, rec, http.StatusOK)
+	var res struct {
+		Fields []fieldSchema `json:"fields"`
+	}
+	decode(t, rec, &res)
+
+	want := map[string]fieldSchema{
+		"Id":        {Type: "integer", ReadOnly: true},
+		"Title":     {Type: "string", Required: true},
+		"Completed": {Type: "boolean"},
+		"Color":     {Type: "string", Enum: colorPalette, Pattern: hexColor.String()},
+		"Priority":  {Type: "string", Enum: priorities},
+		"StartDate": {Type: "string", Format: "date-time", Nullable: true},
+		"Due":       {Type: "string", Format: "date-time", Nullable: true},
+		"Tags":      {Type: "array", Nullable: true},
+		"ParentId":  {Type: "integer", Nullable: true},
+		"CreatedAt": {Type: "string", Format: "date-time", ReadOnly: true},
+		"UpdatedAt": {Type: "string", Format: "date-time", ReadOnly: true},
+		"DeletedAt": {Type: "string", Format: "date-time", ReadOnly: true, Nullable: true},
+	}
+	if len(res.Fields) != len(want) {
+		t.Errorf("schema has %d fields, want %d", len(res.Fields), len(want))
+	}
+	for _, field := range res.Fields {
+		wantField, ok := want[field.Name]
+		if !ok {
+			t.Errorf("schema has unexpected field %q", field.Name)
+			continue
+		}
+		wantField.Name = field.Name
+		if !reflect.DeepEqual(field, wantField) {
+			t.Errorf("schema has %+v, want %+v", field, wantField)
+		}
+	}
+}
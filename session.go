@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	sessionHeader = "X-Brain-Session"
+	sessionCookie = "brain_session"
+)
+
+type contextKey string
+
+const sessionContextKey contextKey = "session"
+
+type session struct {
+	lastSeen time.Time
+	touched  map[int]bool
+}
+
+// sessionStore remembers which tasks each session created or modified.
+// Sessions live in memory only and are forgotten after ttl of inactivity.
+type sessionStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions map[string]*session
+}
+
+func newSessionStore(ttl time.Duration) *sessionStore {
+	return &sessionStore{
+		ttl:      ttl,
+		sessions: make(map[string]*session),
+	}
+}
+
+func (ss *sessionStore) touch(id string, taskId int, now time.Time) {
+	if id == "" {
+		return
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.expire(now)
+	s, ok := ss.sessions[id]
+	if !ok {
+		s = &session{touched: make(map[int]bool)}
+		ss.sessions[id] = s
+	}
+	s.lastSeen = now
+	s.touched[taskId] = true
+}
+
+// touchedIds returns the IDs of tasks touched by the session, which is
+// empty for an unknown or expired session.
+func (ss *sessionStore) touchedIds(id string, now time.Time) map[int]bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.expire(now)
+	touched := make(map[int]bool)
+	if s, ok := ss.sessions[id]; ok {
+		s.lastSeen = now
+		for taskId := range s.touched {
+			touched[taskId] = true
+		}
+	}
+	return touched
+}
+
+// expire drops inactive sessions. Callers must hold ss.mu.
+func (ss *sessionStore) expire(now time.Time) {
+	for id, s := range ss.sessions {
+		if now.Sub(s.lastSeen) >= ss.ttl {
+			delete(ss.sessions, id)
+		}
+	}
+}
+
+// trackSession identifies the client's session from the X-Brain-Session
+// header or the brain_session cookie, issuing a new cookie when neither is
+// present, and makes it available to handlers via sessionId.
+func (app *application) trackSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(sessionHeader)
+		if id == "" {
+			if cookie, err := r.Cookie(sessionCookie); err == nil {
+				id = cookie.Value
+			}
+		}
+
+		if id == "" {
+			b := make([]byte, 16)
+			_, err := rand.Read(b)
+			if err != nil {
+				log.Print(err.Error())
+			} else {
+				// Browsers don't send Secure cookies over plain HTTP, so
+				// with TLS_ENABLED=false the cookie can't be Secure.
+				id = hex.EncodeToString(b)
+				http.SetCookie(w, &http.Cookie{
+					Name:     sessionCookie,
+					Value:    id,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), sessionContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func sessionId(r *http.Request) string {
+	id, _ := r.Context().Value(sessionContextKey).(string)
+	return id
+}
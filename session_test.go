@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+// doAs is do from the session with id.
+func doAs(app *application, id, method, target, body string) []byte {
+	req := newRequest(method, target, body)
+	req.Header.Set(sessionHeader, id)
+	return serve(app, req).Body.Bytes()
+}
+
+func TestSessionChanged(t *testing.T) {
+	app, clock := newTestApp(t)
+	doAs(app, "alice", "POST", "/tasks", `{"Title":"Buy milk"}`)
+	doAs(app, "bob", "POST", "/tasks", `{"Title":"Buy eggs"}`)
+	doAs(app, "bob", "POST", "/tasks", `{"Title":"Buy bread"}`)
+	doAs(app, "alice", "PATCH", "/tasks/3", `{"Completed":true}`)
+
+	sessionIds := func(id string) []int {
+		req := newRequest("GET", "/tasks/ids?session_changed=true", "")
+		req.Header.Set(sessionHeader, id)
+		rec := serve(app, req)
+		expectStatus(t, rec, http.StatusOK)
+		var ids []int
+		decode(t, rec, &ids)
+		return ids
+	}
+	if got, want := sessionIds("alice"), []int{1, 3}; !slices.Equal(got, want) {
+		t.Errorf("alice changed %v, want %v", got, want)
+	}
+	if got, want := sessionIds("bob"), []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("bob changed %v, want %v", got, want)
+	}
+	if got := sessionIds("carol"); len(got) != 0 {
+		t.Errorf("carol changed %v, want nothing", got)
+	}
+
+	// Sessions are forgotten after a day without requests.
+	clock.Advance(25 * time.Hour)
+	if got := sessionIds("alice"); len(got) != 0 {
+		t.Errorf("expired session still changed %v", got)
+	}
+}
+
+func TestTrackSessionIssuesCookie(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	rec := do(app, "POST", "/tasks", `{"Title":"Buy milk"}`)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || cookies[0].Value == "" {
+		t.Fatalf("got cookies %v, want a session cookie", cookies)
+	}
+
+	req := newRequest("GET", "/tasks?session_changed=true", "")
+	req.AddCookie(cookies[0])
+	rec = serve(app, req)
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "Buy milk" {
+		t.Errorf("session cookie listed %+v, want Buy milk", tasks)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("issued another cookie to a request that had one")
+	}
+}
+
+func TestSessionCookieSecureOnlyOverTLS(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	for name, state := range map[string]*tls.ConnectionState{"plain HTTP": nil, "TLS": {}} {
+		req := newRequest("GET", "/tasks", "")
+		req.TLS = state
+		cookies := serve(app, req).Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("%s: got cookies %v, want a session cookie", name, cookies)
+		}
+		if cookies[0].Secure != (state != nil) {
+			t.Errorf("%s: session cookie has Secure %v", name, cookies[0].Secure)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore keeps tasks in a SQLite database, one row per task holding
+// its JSON encoding, so multi-task writes can run in a transaction.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func openSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer. One connection queues writes in
+	// database/sql rather than failing them with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS tasks (id INTEGER PRIMARY KEY, task TEXT NOT NULL)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (store *SQLiteStore) Create(tasks []Task) ([]Task, error) {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var maxId int
+	err = tx.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM tasks`).Scan(&maxId)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]Task, len(tasks))
+	for index, task := range tasks {
+		task.Id = maxId + 1 + index
+		taskJson, err := json.Marshal(task)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(`INSERT INTO tasks (id, task) VALUES (?, ?)`, task.Id, string(taskJson))
+		if err != nil {
+			return nil, err
+		}
+		created[index] = task
+	}
+
+	return created, tx.Commit()
+}
+
+func (store *SQLiteStore) Get(taskId int) (Task, error) {
+	var taskJson string
+	err := store.db.QueryRow(`SELECT task FROM tasks WHERE id = ?`, taskId).Scan(&taskJson)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, store.notFound(taskId)
+	}
+	if err != nil {
+		return Task{}, err
+	}
+
+	task, err := decodeTask([]byte(taskJson))
+	if isInvalidTask(err) || (err == nil && task.Id != taskId) {
+		store.logInvalid(taskId, task, err)
+		return Task{}, store.notFound(taskId)
+	}
+	return task, err
+}
+
+func (store *SQLiteStore) List() ([]Task, error) {
+	rows, err := store.db.Query(`SELECT id, task FROM tasks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []Task{}
+	for rows.Next() {
+		var taskId int
+		var taskJson string
+		err = rows.Scan(&taskId, &taskJson)
+		if err != nil {
+			return nil, err
+		}
+		task, err := decodeTask([]byte(taskJson))
+		if isInvalidTask(err) || (err == nil && task.Id != taskId) {
+			store.logInvalid(taskId, task, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+func (store *SQLiteStore) Update(task Task) error {
+	taskJson, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	_, err = store.db.Exec(`INSERT INTO tasks (id, task) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET task = excluded.task`, task.Id, string(taskJson))
+	return err
+}
+
+func (store *SQLiteStore) Delete(taskId int) error {
+	res, err := store.db.Exec(`DELETE FROM tasks WHERE id = ?`, taskId)
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return store.notFound(taskId)
+	}
+	return nil
+}
+
+// Ping takes and releases the database's write lock, which fails if the
+// file is missing, read-only or locked by another process.
+func (store *SQLiteStore) Ping() error {
+	ctx := context.Background()
+	conn, err := store.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `BEGIN IMMEDIATE`)
+	if err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `ROLLBACK`)
+	return err
+}
+
+// logInvalid logs a row that reads skip, like newFileStore does for task
+// files, so one bad row can't break every list. Create numbers new tasks
+// after the highest row ID, so a skipped row is never overwritten.
+func (store *SQLiteStore) logInvalid(taskId int, task Task, err error) {
+	if err != nil {
+		log.Printf("skipping task row %d: invalid task JSON: %s", taskId, err.Error())
+		return
+	}
+	log.Printf("skipping task row %d: contains task %v", taskId, task.Id)
+}
+
+func (store *SQLiteStore) notFound(taskId int) error {
+	return fmt.Errorf("task %d: %w", taskId, fs.ErrNotExist)
+}
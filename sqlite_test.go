@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestSQLiteStore opens a SQLite store in a temporary directory.
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+
+	store, err := openSQLiteStore(filepath.Join(t.TempDir(), "brain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.db.Close() })
+	return store
+}
+
+func TestSQLiteStoreDefaultsMissingPriority(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	_, err := store.db.Exec(`INSERT INTO tasks (id, task) VALUES (1, '{"Id":1,"Title":"Water plants"}')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Priority != defaultPriority {
+		t.Errorf("Get returned priority %q, want %q", task.Priority, defaultPriority)
+	}
+
+	app, _ := newTestApp(t)
+	app.store = store
+	rec := do(app, "GET", "/tasks?priority="+defaultPriority, "")
+	expectStatus(t, rec, http.StatusOK)
+	var tasks []Task
+	decode(t, rec, &tasks)
+	if len(tasks) != 1 || tasks[0].Priority != defaultPriority {
+		t.Errorf("listed %+v, want task 1 with priority %q", tasks, defaultPriority)
+	}
+}
+
+func TestSQLiteStoreSkipsInvalidRows(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	_, err := store.db.Exec(`INSERT INTO tasks (id, task) VALUES
+		(1, '{"Id":1,"Title":"Water plants"}'),
+		(2, 'not json'),
+		(3, '{"Id":3,"Title":7}'),
+		(4, '{"Id":9,"Title":"In the wrong row"}'),
+		(5, '{"Id":5,"Title":"Bad date","Due":"tomorrow"}')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := captureLogs(t)
+
+	tasks, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Id != 1 {
+		t.Errorf("listed %+v, want only task 1", tasks)
+	}
+	for _, taskId := range []int{2, 3, 4, 5} {
+		_, err = store.Get(taskId)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("getting task %d returned %v, want a not found error", taskId, err)
+		}
+		if want := fmt.Sprintf("skipping task row %d", taskId); !strings.Contains(logs.String(), want) {
+			t.Errorf("logs %q don't mention %q", logs.String(), want)
+		}
+	}
+
+	created, err := store.Create([]Task{{Title: "Feed cat"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created[0].Id != 6 {
+		t.Errorf("created task %d, want 6 so no skipped row is overwritten", created[0].Id)
+	}
+
+	app, _ := newTestApp(t)
+	app.store = store
+	for _, target := range []string{"/tasks", "/tasks/ids", "/tasks/export"} {
+		expectStatus(t, do(app, "GET", target, ""), http.StatusOK)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// Front-end build output placed in ./static is compiled into the binary.
+// The directory only holds a placeholder by default, in which case no
+// static route is registered.
+//
+//go:embed all:static
+var staticFiles embed.FS
+
+const defaultStaticPath = "/app/"
+
+// staticHandler returns a file server for the front-end in assets mounted
+// at prefix (which must end in a slash), or false if there is no front-end
+// in assets, as when only the placeholder has been embedded.
+func staticHandler(assets fs.FS, prefix string) (http.Handler, bool) {
+	if assets == nil {
+		return nil, false
+	}
+
+	_, err := fs.Stat(assets, "index.html")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false
+	}
+	if err != nil {
+		log.Print(err.Error())
+		return nil, false
+	}
+
+	return http.StripPrefix(prefix, http.FileServer(http.FS(assets))), true
+}
+
+// staticPathRoute returns the pattern of the API route in mux that serves
+// prefix now. Mounting the front-end anywhere "/" doesn't serve would
+// either register a pattern twice, which makes ServeMux panic, or take
+// part of a subtree like /tasks/ away from the API.
+func staticPathRoute(mux *http.ServeMux, prefix string) string {
+	_, pattern := mux.Handler(&http.Request{Method: "GET", URL: &url.URL{Path: prefix}})
+	return pattern
+}
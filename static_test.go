@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticPathRoute(t *testing.T) {
+	app, _ := newTestApp(t)
+	mux := app.apiRoutes()
+
+	for prefix, want := range map[string]string{
+		"/app/":          "/",
+		"/admin/":        "/",
+		"/healthz/":      "/",
+		"/tasks/":        "/tasks/",
+		"/tasks/1/":      "/tasks/",
+		"/tasks/schema/": "/tasks/",
+		"/focus/":        "/focus/",
+		"/focus/pinned/": "/focus/",
+	} {
+		if got := staticPathRoute(mux, prefix); got != want {
+			t.Errorf("%s is served by %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestStaticFrontEnd(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.assets = fstest.MapFS{
+		"index.html": {Data: []byte("<h1>brain</h1>")},
+		"app.js":     {Data: []byte("console.log('brain')")},
+	}
+
+	rec := do(app, "GET", "/app/app.js", "")
+	expectStatus(t, rec, http.StatusOK)
+	if rec.Body.String() != "console.log('brain')" {
+		t.Errorf("served %q for app.js", rec.Body.String())
+	}
+	expectStatus(t, do(app, "GET", "/app/", ""), http.StatusOK)
+	expectStatus(t, do(app, "GET", "/tasks", ""), http.StatusOK)
+
+	// The front-end is behind basic auth like the API.
+	expectStatus(t, serve(app, httptest.NewRequest("GET", "/app/app.js", nil)), http.StatusUnauthorized)
+}
+
+func TestStaticWithoutFrontEnd(t *testing.T) {
+	app, _ := newTestApp(t)
+	assets, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.assets = assets
+
+	expectStatus(t, do(app, "GET", "/app/", ""), http.StatusNotFound)
+	expectStatus(t, do(app, "GET", "/tasks", ""), http.StatusOK)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TaskStore persists tasks. Handlers only go through it, so the backing
+// storage is chosen with BRAIN_STORE. Errors for a missing task wrap
+// fs.ErrNotExist.
+type TaskStore interface {
+	// Create stores tasks under new consecutive IDs, following the highest
+	// ID in use, and returns them with their IDs set. Either every task is
+	// stored or none is.
+	Create(tasks []Task) ([]Task, error)
+
+	// Get returns a task whether or not it is in the trash.
+	Get(taskId int) (Task, error)
+
+	// List returns every task, trashed ones included, by ascending ID.
+	List() ([]Task, error)
+
+	// Update stores task under its ID, replacing any task already there.
+	Update(task Task) error
+
+	// Delete removes a task for good, rather than moving it to the trash.
+	Delete(taskId int) error
+
+	// Ping reports whether the store can currently accept writes.
+	Ping() error
+}
+
+// FileStore keeps each task in its own <id>.json file in dir, with every
+// task also held in an in-memory index so reads don't touch the disk.
+// Files are written first and the index updated to match.
+type FileStore struct {
+	dir    string
+	files  taskFS
+	pretty bool
+	index  *taskIndex
+
+	// skippedId is the highest ID of a file skipped as invalid at load, so
+	// Create never overwrites one.
+	skippedId int
+
+	// mu serializes writes, so the next ID Create picks stays free until
+	// its files are written.
+	mu sync.Mutex
+}
+
+// newFileStore reads every task file in dir into the index, logging and
+// skipping any that isn't a valid task so one bad file can't take the
+// server down. With pretty set, files are written indented for reading
+// and editing by hand.
+func newFileStore(dir string, files taskFS, pretty bool) (*FileStore, error) {
+	store := &FileStore{dir: dir, files: files, pretty: pretty, index: newTaskIndex()}
+
+	taskFiles, err := filepath.Glob(store.taskPath("*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range taskFiles {
+		taskId, err := taskIdFromPath(file)
+		if err != nil {
+			log.Printf("skipping %s: not named <id>.json", file)
+			continue
+		}
+		task, err := store.readTaskFile(taskId)
+		if isInvalidTask(err) {
+			log.Printf("skipping %s: invalid task JSON: %s", file, err.Error())
+			store.skippedId = max(store.skippedId, taskId)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if task.Id != taskId {
+			log.Printf("skipping %s: contains task %v", file, task.Id)
+			store.skippedId = max(store.skippedId, taskId)
+			continue
+		}
+		store.index.put(task)
+	}
+
+	return store, nil
+}
+
+func (store *FileStore) Create(tasks []Task) ([]Task, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	nextId := store.skippedId + 1
+	if ids := store.index.ids(); len(ids) > 0 {
+		nextId = max(nextId, ids[len(ids)-1]+1)
+	}
+
+	created := make([]Task, len(tasks))
+	for index, task := range tasks {
+		task.Id = nextId + index
+		created[index] = task
+	}
+
+	for index, task := range created {
+		err := store.write(task)
+		if err != nil {
+			// Undo the tasks already written so a failed batch creates nothing.
+			for _, written := range created[:index] {
+				store.files.Remove(store.taskPath(written.Id))
+			}
+			return nil, err
+		}
+	}
+	for _, task := range created {
+		store.index.put(task)
+	}
+
+	return created, nil
+}
+
+func (store *FileStore) Get(taskId int) (Task, error) {
+	task, ok := store.index.get(taskId)
+	if !ok {
+		return task, store.notFound(taskId)
+	}
+	return task, nil
+}
+
+func (store *FileStore) List() ([]Task, error) {
+	ids := store.index.ids()
+	tasks := make([]Task, 0, len(ids))
+	for _, taskId := range ids {
+		task, ok := store.index.get(taskId)
+		if ok {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (store *FileStore) Update(task Task) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.write(task)
+	if err != nil {
+		return err
+	}
+	store.index.put(task)
+	return nil
+}
+
+func (store *FileStore) Delete(taskId int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := store.files.Remove(store.taskPath(taskId))
+	if err != nil {
+		return err
+	}
+	store.index.remove(taskId)
+	return nil
+}
+
+// Ping creates and removes a temporary file in the tasks directory.
+func (store *FileStore) Ping() error {
+	file, err := os.CreateTemp(store.dir, ".healthz-*")
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	err = file.Close()
+	removeErr := os.Remove(name)
+	if err != nil {
+		return err
+	}
+	return removeErr
+}
+
+// write saves task to its file. Files are compact by default; pretty
+// indents them so they are easier to edit by hand. Reads go through
+// json.Unmarshal, which accepts either form.
+func (store *FileStore) write(task Task) error {
+	var taskJson []byte
+	var err error
+	if store.pretty {
+		taskJson, err = json.MarshalIndent(task, "", "  ")
+	} else {
+		taskJson, err = json.Marshal(task)
+	}
+	if err != nil {
+		return err
+	}
+	return store.files.WriteFile(store.taskPath(task.Id), taskJson, 0644)
+}
+
+// readTaskFile reads a task from disk, bypassing the index.
+func (store *FileStore) readTaskFile(taskId int) (Task, error) {
+	taskJson, err := store.files.ReadFile(store.taskPath(taskId))
+	if err != nil {
+		return Task{}, err
+	}
+	return decodeTask(taskJson)
+}
+
+// decodeTask unmarshals a task as every store saves it. Tasks saved before
+// priorities existed have none, so they get the default.
+func decodeTask(taskJson []byte) (Task, error) {
+	var task Task
+	err := json.Unmarshal(taskJson, &task)
+	if err != nil {
+		return task, &invalidTaskError{err: err}
+	}
+	if task.Priority == "" {
+		task.Priority = defaultPriority
+	}
+	return task, nil
+}
+
+// invalidTaskError is decodeTask failing on something that isn't a task,
+// whether malformed JSON, a mistyped field or a timestamp that doesn't
+// parse.
+type invalidTaskError struct {
+	err error
+}
+
+func (e *invalidTaskError) Error() string {
+	return e.err.Error()
+}
+
+func (e *invalidTaskError) Unwrap() error {
+	return e.err
+}
+
+// isInvalidTask reports whether err is decodeTask failing on something
+// that isn't a task, as opposed to the read itself failing.
+func isInvalidTask(err error) bool {
+	var invalidErr *invalidTaskError
+	return errors.As(err, &invalidErr)
+}
+
+func (store *FileStore) taskPath(taskId interface{}) string {
+	return fmt.Sprintf("%v/%v.json", store.dir, taskId)
+}
+
+// notFound is the error reads return for a task missing from the index,
+// matching what reading a missing file would return.
+func (store *FileStore) notFound(taskId int) error {
+	return &fs.PathError{Op: "open", Path: store.taskPath(taskId), Err: fs.ErrNotExist}
+}
+
+func taskIdFromPath(file string) (int, error) {
+	filename := path.Base(file)
+	return strconv.Atoi(strings.Split(filename, ".")[0])
+}
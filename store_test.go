@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStorePretty(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newFileStore(dir, osFS{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Create([]Task{{Title: "Water plants", Priority: defaultPriority}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("\n  \"Title\": \"Water plants\",\n")) {
+		t.Errorf("file isn't indented:\n%s", data)
+	}
+
+	// A compact file alongside an indented one loads just the same.
+	err = os.WriteFile(filepath.Join(dir, "2.json"), []byte(`{"Id":2,"Title":"Feed cat","Priority":"low"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err = newFileStore(dir, osFS{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "Water plants" || tasks[1].Title != "Feed cat" {
+		t.Errorf("loaded %+v, want both tasks", tasks)
+	}
+}
+
+func TestFileStoreNumericOrder(t *testing.T) {
+	dir := t.TempDir()
+	for _, id := range []string{"1", "2", "10", "11"} {
+		task := `{"Id":` + id + `,"Title":"Task ` + id + `","Priority":"low"}`
+		err := os.WriteFile(filepath.Join(dir, id+".json"), []byte(task), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := newFileStore(dir, osFS{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []int{}
+	for _, task := range tasks {
+		ids = append(ids, task.Id)
+	}
+	if !slices.Equal(ids, []int{1, 2, 10, 11}) {
+		t.Errorf("listed IDs %v, want 1, 2, 10, 11", ids)
+	}
+
+	// A lexical scan would take 2 as the highest ID and reuse 3.
+	created, err := store.Create([]Task{{Title: "Task 12", Priority: defaultPriority}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created[0].Id != 12 {
+		t.Errorf("created task %v, want 12", created[0].Id)
+	}
+}
+
+// testStores opens each TaskStore implementation, empty, for tests that
+// check they behave the same.
+var testStores = map[string]func(t *testing.T) TaskStore{
+	"file": func(t *testing.T) TaskStore {
+		store, err := newFileStore(t.TempDir(), osFS{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	},
+	"sqlite": func(t *testing.T) TaskStore {
+		return newTestSQLiteStore(t)
+	},
+}
+
+func TestTaskStores(t *testing.T) {
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+
+			err := store.Ping()
+			if err != nil {
+				t.Fatalf("pinging an empty store: %v", err)
+			}
+			tasks, err := store.List()
+			if err != nil || len(tasks) != 0 {
+				t.Fatalf("empty store listed %+v, error %v", tasks, err)
+			}
+			_, err = store.Get(1)
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("getting a missing task returned %v, want fs.ErrNotExist", err)
+			}
+
+			// Batches are numbered in order, after the highest ID.
+			created, err := store.Create([]Task{
+				{Title: "Water plants", Priority: defaultPriority, CreatedAt: testNow, UpdatedAt: testNow},
+				{Title: "Feed cat", Priority: "high", Tags: []string{"home"}, CreatedAt: testNow, UpdatedAt: testNow},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(created) != 2 || created[0].Id != 1 || created[1].Id != 2 {
+				t.Fatalf("created %+v, want tasks 1 and 2", created)
+			}
+			task, err := store.Get(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(task, created[1]) {
+				t.Errorf("got %+v, want %+v", task, created[1])
+			}
+
+			due := testNow.Add(24 * time.Hour)
+			task.Title, task.Due, task.Completed = "Feed the cat", &due, true
+			err = store.Update(task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			updated, err := store.Get(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(updated, task) {
+				t.Errorf("updated to %+v, want %+v", updated, task)
+			}
+
+			err = store.Delete(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.Delete(1)
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("deleting a missing task returned %v, want fs.ErrNotExist", err)
+			}
+			_, err = store.Get(1)
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("getting a deleted task returned %v, want fs.ErrNotExist", err)
+			}
+
+			created, err = store.Create([]Task{{Title: "Walk dog", Priority: defaultPriority}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created[0].Id != 3 {
+				t.Errorf("created task %d after deleting 1, want 3", created[0].Id)
+			}
+			tasks, err = store.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != 2 || !reflect.DeepEqual(tasks[0], updated) || tasks[1].Id != 3 {
+				t.Errorf("listed %+v, want tasks 2 and 3 in order", tasks)
+			}
+		})
+	}
+}
+
+func TestHandlersWithEachStore(t *testing.T) {
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			app.store = open(t)
+
+			createTask(t, app, `{"Title":"Water plants"}`)
+			expectStatus(t, do(app, "POST", "/tasks", `[{"Title":"Feed cat"},{"Title":"Walk dog"}]`), http.StatusOK)
+			expectStatus(t, do(app, "PATCH", "/tasks/2", `{"Completed":true}`), http.StatusOK)
+			expectStatus(t, do(app, "DELETE", "/tasks/3", ""), http.StatusNoContent)
+
+			if got := listIds(t, app, "/tasks"); !slices.Equal(got, []int{1, 2}) {
+				t.Errorf("listed %v, want [1 2]", got)
+			}
+			if got := listIds(t, app, "/tasks?completed=true"); !slices.Equal(got, []int{2}) {
+				t.Errorf("listed %v completed, want [2]", got)
+			}
+			expectStatus(t, do(app, "GET", "/tasks/3", ""), http.StatusNotFound)
+		})
+	}
+}
+
+func TestFileStoreSkipsInvalidFiles(t *testing.T) {
+	for name, contents := range map[string]string{
+		"malformed JSON": `{"Id":2,"Title":`,
+		"bad timestamp":  `{"Id":2,"Title":"bad","Due":"tomorrow"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.WriteFile(filepath.Join(dir, "1.json"), []byte(`{"Id":1,"Title":"Water plants"}`), 0644)
+			if err == nil {
+				err = os.WriteFile(filepath.Join(dir, "2.json"), []byte(contents), 0644)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			logs := captureLogs(t)
+
+			app, _ := newTestApp(t)
+			app.store, err = newFileStore(dir, osFS{}, false)
+			if err != nil {
+				t.Fatalf("a bad task file stopped the store loading: %v", err)
+			}
+			if !strings.Contains(logs.String(), "skipping "+filepath.Join(dir, "2.json")) {
+				t.Errorf("logs %q don't name the skipped file", logs.String())
+			}
+
+			if got := listIds(t, app, "/tasks"); !slices.Equal(got, []int{1}) {
+				t.Errorf("listed %v, want only the valid task 1", got)
+			}
+			expectStatus(t, do(app, "GET", "/tasks/2", ""), http.StatusNotFound)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+)
+
+// checkParent validates task's ParentId: the parent must be a task that
+// isn't in the trash, other than task itself and none of its subtasks,
+// and with BRAIN_MAX_SUBTASK_DEPTH task must not be nested deeper than
+// that. The error is a *malformedRequest unless reading a task failed.
+func (app *application) checkParent(task Task) error {
+	if task.ParentId == nil {
+		return nil
+	}
+
+	// New tasks have no ID yet, so they can't be their own parent.
+	parentId := *task.ParentId
+	if task.Id != 0 && parentId == task.Id {
+		return &malformedRequest{status: http.StatusBadRequest, msg: "A task cannot be its own parent"}
+	}
+
+	// Walk up from the parent, counting how deep task would be. Reaching
+	// task means it would become its own ancestor. A missing ancestor ends
+	// the chain, and the visited set stops the walk on cycles already on
+	// disk, which restoring a backup could bring in.
+	depth := 1
+	visited := map[int]bool{}
+	for ancestorId := parentId; !visited[ancestorId]; depth++ {
+		visited[ancestorId] = true
+
+		ancestor, err := app.readTask(ancestorId)
+		if errors.Is(err, os.ErrNotExist) && ancestorId == parentId {
+			msg := fmt.Sprintf("Parent task with ID %v not found", parentId)
+			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			// The task pointing here is the top of the chain.
+			depth--
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if ancestor.ParentId == nil {
+			break
+		}
+		ancestorId = *ancestor.ParentId
+		if ancestorId == task.Id {
+			msg := fmt.Sprintf("Task with ID %v is a subtask of task %v, so it cannot be its parent", parentId, task.Id)
+			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+	}
+
+	return app.checkSubtaskDepth(depth)
+}
+
+// checkSubtaskDepth rejects a subtask depth, 1 for a child of a top-level
+// task, over BRAIN_MAX_SUBTASK_DEPTH.
+func (app *application) checkSubtaskDepth(depth int) error {
+	if app.maxSubtaskDepth > 0 && depth > app.maxSubtaskDepth {
+		msg := fmt.Sprintf("Subtask would be nested %d levels deep, must be at most %d", depth, app.maxSubtaskDepth)
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+	return nil
+}
+
+// children returns every task whose ParentId is taskId, trashed ones
+// included.
+func (app *application) children(taskId int) ([]Task, error) {
+	tasks, err := app.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	children := []Task{}
+	for _, task := range tasks {
+		if task.ParentId != nil && *task.ParentId == taskId {
+			children = append(children, task)
+		}
+	}
+	return children, nil
+}
+
+// subtasks lists the direct children of a task that aren't in the trash.
+func (app *application) subtasks(w http.ResponseWriter, r *http.Request, taskId int) {
+	format, err := negotiateFormat(r)
+	if err != nil {
+		var mr *malformedRequest
+		errors.As(err, &mr)
+		writeJSONError(w, mr.status, mr.msg)
+		return
+	}
+
+	_, err = app.readTask(taskId)
+	if errors.Is(err, os.ErrNotExist) {
+		msg := fmt.Sprintf("Task with ID %v not found", taskId)
+		writeJSONError(w, http.StatusNotFound, msg)
+		return
+	}
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving subtasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	children, err := app.children(taskId)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving subtasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	subtasks := []Task{}
+	for _, child := range children {
+		if child.DeletedAt == nil {
+			subtasks = append(subtasks, child)
+		}
+	}
+
+	err = writeTasks(w, format, subtasks, app.clock.Now())
+	if err != nil {
+		log.Print(err.Error())
+	}
+}
+
+// maxExpandDepth is how many levels of subtasks ?expand=subtasks nests.
+const maxExpandDepth = 10
+
+// taskTree is a task with its subtasks nested, for ?expand=subtasks.
+type taskTree struct {
+	agedTask
+	Subtasks []taskTree `json:"subtasks"`
+
+	// Truncated marks a task whose subtasks are deeper than maxExpandDepth,
+	// and so were left out.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// subtaskTree nests the subtasks of task that aren't in the trash, down to
+// maxExpandDepth. Each task appears once, so a cycle in the parents on
+// disk, which restoring a backup could bring in, ends the branch rather
+// than repeating forever.
+func (app *application) subtaskTree(task Task, includeAge bool) (taskTree, error) {
+	tasks, err := app.store.List()
+	if err != nil {
+		return taskTree{}, err
+	}
+	children := map[int][]Task{}
+	for _, child := range tasks {
+		if child.ParentId != nil && child.DeletedAt == nil {
+			children[*child.ParentId] = append(children[*child.ParentId], child)
+		}
+	}
+
+	now := app.clock.Now()
+	visited := map[int]bool{}
+	var expand func(task Task, depth int) taskTree
+	expand = func(task Task, depth int) taskTree {
+		visited[task.Id] = true
+		node := taskTree{agedTask: agedTask{Task: task}, Subtasks: []taskTree{}}
+		if includeAge {
+			node.agedTask = withAge(task, now)
+		}
+		for _, child := range children[task.Id] {
+			if visited[child.Id] {
+				continue
+			}
+			if depth == maxExpandDepth {
+				node.Truncated = true
+				break
+			}
+			node.Subtasks = append(node.Subtasks, expand(child, depth+1))
+		}
+		return node
+	}
+	return expand(task, 0), nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCreateChecksParent(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Plan trip"}`)
+
+	child := createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+	if child.ParentId == nil || *child.ParentId != 1 {
+		t.Errorf("created %+v, want ParentId 1", child)
+	}
+	expectStatus(t, do(app, "POST", "/tasks", `{"Title":"Pack","ParentId":99}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "POST", "/tasks", `[{"Title":"Pack"},{"Title":"Unpack","ParentId":99}]`), http.StatusBadRequest)
+}
+
+func TestCreateReportsParentReadErrors(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	app.store = brokenStore{TaskStore: app.store, getErr: errors.New("disk on fire")}
+
+	expectStatus(t, do(app, "POST", "/tasks", `{"Title":"Book flights","ParentId":1}`), http.StatusInternalServerError)
+	expectStatus(t, do(app, "POST", "/tasks", `[{"Title":"Book flights","ParentId":1}]`), http.StatusInternalServerError)
+}
+
+func TestUpdateRejectsParentCycles(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+	createTask(t, app, `{"Title":"Compare prices","ParentId":2}`)
+
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"ParentId":1}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"ParentId":3}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "PATCH", "/tasks/3", `{"ParentId":1}`), http.StatusOK)
+}
+
+func TestSubtasks(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+	createTask(t, app, `{"Title":"Book hotel","ParentId":1}`)
+	createTask(t, app, `{"Title":"Compare prices","ParentId":2}`)
+	expectStatus(t, do(app, "DELETE", "/tasks/3", ""), http.StatusNoContent)
+
+	rec := do(app, "GET", "/tasks/1/subtasks", "")
+	expectStatus(t, rec, http.StatusOK)
+	var subtasks []Task
+	decode(t, rec, &subtasks)
+	if len(subtasks) != 1 || subtasks[0].Id != 2 {
+		t.Errorf("listed subtasks %+v, want only task 2", subtasks)
+	}
+	expectStatus(t, do(app, "GET", "/tasks/99/subtasks", ""), http.StatusNotFound)
+}
+
+func TestDeleteMovesSubtasksUp(t *testing.T) {
+	app, clock := newTestApp(t)
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+	createTask(t, app, `{"Title":"Compare prices","ParentId":2}`)
+	clock.Advance(time.Minute)
+
+	expectStatus(t, do(app, "DELETE", "/tasks/2", ""), http.StatusNoContent)
+	task, err := app.store.Get(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ParentId == nil || *task.ParentId != 1 {
+		t.Errorf("subtask of a deleted task has ParentId %v, want 1", task.ParentId)
+	}
+	if !task.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("moved subtask has UpdatedAt %v, want %v", task.UpdatedAt, clock.Now())
+	}
+
+	expectStatus(t, do(app, "DELETE", "/tasks/1", ""), http.StatusNoContent)
+	task, err = app.store.Get(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ParentId != nil {
+		t.Errorf("subtask of a deleted top-level task has ParentId %v, want none", *task.ParentId)
+	}
+}
+
+func TestListHidesSubtasks(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+	createTask(t, app, `{"Title":"Buy milk"}`)
+
+	if got, want := listIds(t, app, "/tasks"), []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("by default got %v, want %v", got, want)
+	}
+	if got, want := listIds(t, app, "/tasks?include_subtasks=false"), []int{1, 3}; !slices.Equal(got, want) {
+		t.Errorf("with include_subtasks=false got %v, want %v", got, want)
+	}
+
+	app.hideSubtasks = true
+	if got, want := listIds(t, app, "/tasks"), []int{1, 3}; !slices.Equal(got, want) {
+		t.Errorf("with BRAIN_HIDE_SUBTASKS got %v, want %v", got, want)
+	}
+	if got, want := listIds(t, app, "/tasks?include_subtasks=true"), []int{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("with include_subtasks=true got %v, want %v", got, want)
+	}
+	expectStatus(t, do(app, "GET", "/tasks?include_subtasks=maybe", ""), http.StatusBadRequest)
+}
+
+func TestShowExpandsSubtasks(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+	createTask(t, app, `{"Title":"Compare prices","ParentId":2}`)
+	createTask(t, app, `{"Title":"Book hotel","ParentId":1}`)
+	createTask(t, app, `{"Title":"Old plan","ParentId":1}`)
+	expectStatus(t, do(app, "DELETE", "/tasks/5", ""), http.StatusNoContent)
+
+	rec := do(app, "GET", "/tasks/1?expand=subtasks", "")
+	expectStatus(t, rec, http.StatusOK)
+	var tree taskTree
+	decode(t, rec, &tree)
+
+	if tree.Title != "Plan trip" || len(tree.Subtasks) != 2 {
+		t.Fatalf("got %+v, want Plan trip with 2 subtasks", tree)
+	}
+	flights, hotel := tree.Subtasks[0], tree.Subtasks[1]
+	if flights.Id != 2 || hotel.Id != 4 || len(hotel.Subtasks) != 0 {
+		t.Errorf("subtasks are %+v and %+v", flights, hotel)
+	}
+	if len(flights.Subtasks) != 1 || flights.Subtasks[0].Title != "Compare prices" {
+		t.Errorf("flights has subtasks %+v, want Compare prices", flights.Subtasks)
+	}
+
+	expectStatus(t, do(app, "GET", "/tasks/1?expand=subtasks&format=csv", ""), http.StatusBadRequest)
+	expectStatus(t, do(app, "GET", "/tasks/1?expand=parents", ""), http.StatusBadRequest)
+}
+
+func TestExpandSubtasksBreaksCycles(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Chicken"}`)
+	createTask(t, app, `{"Title":"Egg","ParentId":1}`)
+
+	// The API refuses cycles, but a restored backup could contain one.
+	chicken, err := app.store.Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	egg := 2
+	chicken.ParentId = &egg
+	err = app.store.Update(chicken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(app, "GET", "/tasks/1?expand=subtasks", "")
+	expectStatus(t, rec, http.StatusOK)
+	var tree taskTree
+	decode(t, rec, &tree)
+	if len(tree.Subtasks) != 1 || tree.Subtasks[0].Id != 2 || len(tree.Subtasks[0].Subtasks) != 0 {
+		t.Errorf("got %+v, want Chicken > Egg and nothing more", tree)
+	}
+}
+
+func TestExpandSubtasksDepthLimit(t *testing.T) {
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Level 0"}`)
+	for level := 1; level <= maxExpandDepth+1; level++ {
+		createTask(t, app, fmt.Sprintf(`{"Title":"Level %d","ParentId":%d}`, level, level))
+	}
+
+	rec := do(app, "GET", "/tasks/1?expand=subtasks", "")
+	expectStatus(t, rec, http.StatusOK)
+	var tree taskTree
+	decode(t, rec, &tree)
+
+	depth := 0
+	for node := tree; len(node.Subtasks) > 0; node = node.Subtasks[0] {
+		depth++
+		if depth == maxExpandDepth && !node.Subtasks[0].Truncated {
+			t.Errorf("level %d isn't marked truncated", depth)
+		}
+	}
+	if depth != maxExpandDepth {
+		t.Errorf("nested %d levels, want %d", depth, maxExpandDepth)
+	}
+}
+
+func TestMaxSubtaskDepth(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.maxSubtaskDepth = 2
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+
+	// Two levels below Plan trip is the limit, three is past it.
+	createTask(t, app, `{"Title":"Compare prices","ParentId":2}`)
+	expectStatus(t, do(app, "POST", "/tasks", `{"Title":"Check reviews","ParentId":3}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "POST", "/tasks", `[{"Title":"Check reviews","ParentId":3}]`), http.StatusBadRequest)
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"ParentId":3}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "POST", "/tasks/import/markdown", "- [ ] A\n  - [ ] B\n    - [ ] C\n"), http.StatusOK)
+	expectStatus(t, do(app, "POST", "/tasks/import/markdown", "- [ ] A\n  - [ ] B\n    - [ ] C\n      - [ ] D\n"), http.StatusBadRequest)
+}
+
+func TestMaxSubtaskDepthWithBrokenChain(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.maxSubtaskDepth = 2
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+
+	// Purging Plan trip leaves Book flights pointing at a task that's gone,
+	// which counts as the top of the chain.
+	err := app.store.Delete(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTask(t, app, `{"Title":"Compare prices","ParentId":2}`)
+	createTask(t, app, `{"Title":"Check reviews","ParentId":3}`)
+	expectStatus(t, do(app, "POST", "/tasks", `{"Title":"Read forums","ParentId":4}`), http.StatusBadRequest)
+}
+
+func TestSafeDelete(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.safeDelete = true
+	createTask(t, app, `{"Title":"Plan trip"}`)
+	createTask(t, app, `{"Title":"Book flights","ParentId":1}`)
+	createTask(t, app, `{"Title":"Book hotel","ParentId":1}`)
+	createTask(t, app, `{"Title":"Old plan","ParentId":1}`)
+	expectStatus(t, do(app, "DELETE", "/tasks/4", ""), http.StatusNoContent)
+
+	rec := do(app, "DELETE", "/tasks/1", "")
+	expectStatus(t, rec, http.StatusConflict)
+	var res referencedResponse
+	decode(t, rec, &res)
+	if !slices.Equal(res.ReferencedBy, []int{2, 3}) {
+		t.Errorf("referenced by %v, want [2 3]", res.ReferencedBy)
+	}
+	if task, err := app.store.Get(1); err != nil || task.DeletedAt != nil {
+		t.Fatalf("blocked delete trashed the task: %+v, %v", task, err)
+	}
+
+	expectStatus(t, do(app, "DELETE", "/tasks/1?force=true", ""), http.StatusNoContent)
+	for _, taskId := range []int{2, 3, 4} {
+		task, err := app.store.Get(taskId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.ParentId != nil {
+			t.Errorf("task %d still has parent %v", taskId, *task.ParentId)
+		}
+	}
+
+	// Tasks without subtasks delete as usual.
+	expectStatus(t, do(app, "DELETE", "/tasks/2", ""), http.StatusNoContent)
+}
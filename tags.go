@@ -0,0 +1,33 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// normalizeTags lower-cases and trims tags, dropping empty ones and
+// duplicates while keeping the order they were first given in.
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// hasTags reports whether task carries every one of tags.
+func hasTags(task Task, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(task.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
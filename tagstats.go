@@ -0,0 +1,107 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type tagStat struct {
+	Tag       string `json:"tag"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+
+	// CompletionRate is the percentage of the tag's tasks completed.
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+// tagStatSorters orders tag stats by the keys accepted in ?sort=. As with
+// tasks, prefixing a key with "-" reverses the order.
+var tagStatSorters = map[string]func(a, b tagStat) int{
+	"tag": func(a, b tagStat) int {
+		return cmp.Compare(a.Tag, b.Tag)
+	},
+	"count": func(a, b tagStat) int {
+		return cmp.Compare(a.Total, b.Total)
+	},
+	"rate": func(a, b tagStat) int {
+		return cmp.Compare(a.CompletionRate, b.CompletionRate)
+	},
+}
+
+// tagStats reports how many of each tag's tasks are completed, sorted by
+// ?sort=, highest completion rate first by default. Ties are in tag order.
+// Trashed tasks don't count.
+func (app *application) tagStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		msg := fmt.Sprintf("Unsupported request method %v to /tasks/tags/stats", r.Method)
+		log.Print(msg)
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, msg)
+		return
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = "-rate"
+	}
+	key, descending := strings.CutPrefix(sort, "-")
+	sorter, ok := tagStatSorters[key]
+	if !ok {
+		msg := fmt.Sprintf("Invalid sort %q, must be one of count, rate, tag, optionally prefixed with -", sort)
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tasks, err := app.store.List()
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	byTag := map[string]*tagStat{}
+	for _, task := range tasks {
+		if task.DeletedAt != nil {
+			continue
+		}
+		for _, tag := range task.Tags {
+			stat, ok := byTag[tag]
+			if !ok {
+				stat = &tagStat{Tag: tag}
+				byTag[tag] = stat
+			}
+			stat.Total++
+			if task.Completed {
+				stat.Completed++
+			}
+		}
+	}
+
+	stats := make([]tagStat, 0, len(byTag))
+	for _, stat := range byTag {
+		stat.CompletionRate = float64(stat.Completed) * 100 / float64(stat.Total)
+		stats = append(stats, *stat)
+	}
+	slices.SortFunc(stats, tagStatSorters["tag"])
+	slices.SortStableFunc(stats, func(a, b tagStat) int {
+		if descending {
+			return sorter(b, a)
+		}
+		return sorter(a, b)
+	})
+
+	statsJson, err := json.Marshal(stats)
+	if err != nil {
+		msg := fmt.Sprintf("An error occurred while retrieving tasks, %q", err.Error())
+		writeJSONError(w, http.StatusInternalServerError, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(statsJson)
+}
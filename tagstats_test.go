@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTagStats(t *testing.T) {
+	app, _ := newTestApp(t)
+	for _, body := range []string{
+		`{"Title":"Buy milk","Tags":["errands","home"],"Completed":true}`,
+		`{"Title":"Buy eggs","Tags":["errands"]}`,
+		`{"Title":"Post letter","Tags":["errands"]}`,
+		`{"Title":"Fix sink","Tags":["home"],"Completed":true}`,
+		`{"Title":"File taxes","Tags":["admin"]}`,
+		`{"Title":"Renew passport"}`,
+		`{"Title":"Old errand","Tags":["errands"],"Completed":true}`,
+	} {
+		createTask(t, app, body)
+	}
+	expectStatus(t, do(app, "DELETE", "/tasks/7", ""), http.StatusNoContent)
+
+	admin := tagStat{Tag: "admin", Total: 1}
+	errands := tagStat{Tag: "errands", Total: 3, Completed: 1, CompletionRate: 100.0 / 3}
+	home := tagStat{Tag: "home", Total: 2, Completed: 2, CompletionRate: 100}
+	tests := []struct {
+		target string
+		want   []tagStat
+	}{
+		{"/tasks/tags/stats", []tagStat{home, errands, admin}},
+		{"/tasks/tags/stats?sort=rate", []tagStat{admin, errands, home}},
+		{"/tasks/tags/stats?sort=-count", []tagStat{errands, home, admin}},
+		{"/tasks/tags/stats?sort=tag", []tagStat{admin, errands, home}},
+	}
+	for _, test := range tests {
+		rec := do(app, "GET", test.target, "")
+		expectStatus(t, rec, http.StatusOK)
+		var got []tagStat
+		decode(t, rec, &got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.target, got, test.want)
+		}
+	}
+
+	expectStatus(t, do(app, "GET", "/tasks/tags/stats?sort=color", ""), http.StatusBadRequest)
+}
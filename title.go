@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// normalizeTitle trims a title and collapses runs of whitespace into a
+// single space, optionally upper-casing the first letter as well.
+func normalizeTitle(title string, capitalize bool) string {
+	title = strings.Join(strings.Fields(title), " ")
+
+	if capitalize {
+		first, size := utf8.DecodeRuneInString(title)
+		if first != utf8.RuneError {
+			title = string(unicode.ToUpper(first)) + title[size:]
+		}
+	}
+
+	return title
+}
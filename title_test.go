@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title      string
+		capitalize bool
+		want       string
+	}{
+		{"  buy   milk\t\nand eggs ", false, "buy milk and eggs"},
+		{"  buy   milk ", true, "Buy milk"},
+		{"élan", true, "Élan"},
+		{"Already done", true, "Already done"},
+		{"   ", true, ""},
+	}
+	for _, test := range tests {
+		if got := normalizeTitle(test.title, test.capitalize); got != test.want {
+			t.Errorf("normalizeTitle(%q, %v) = %q, want %q", test.title, test.capitalize, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeTitleOnWrite(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	// Off by default, titles are stored as sent.
+	task := createTask(t, app, `{"Title":"  buy  milk "}`)
+	if task.Title != "  buy  milk " {
+		t.Errorf("created %q without BRAIN_NORMALIZE_TITLE", task.Title)
+	}
+
+	app.normalizeTitle = true
+	task = createTask(t, app, `{"Title":"  buy  eggs "}`)
+	if task.Title != "buy eggs" {
+		t.Errorf("created %q, want whitespace collapsed", task.Title)
+	}
+
+	app.capitalizeTitle = true
+	rec := do(app, "PATCH", "/tasks/2", `{"Title":" feed \t the cat"}`)
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &task)
+	if task.Title != "Feed the cat" {
+		t.Errorf("updated to %q, want normalized and capitalized", task.Title)
+	}
+
+	// Titles that normalize to nothing are still rejected.
+	rec = do(app, "POST", "/tasks", `{"Title":"   "}`)
+	expectStatus(t, rec, http.StatusBadRequest)
+}
+
+func TestTitleLimits(t *testing.T) {
+	app, _ := newTestApp(t)
+	app.titleMaxLength = 30
+	app.titleMaxWords = 3
+
+	tests := []struct {
+		title  string
+		status int
+		error  string
+	}{
+		{"Water the plants", http.StatusCreated, ""},
+		{"  Water   the  plants ", http.StatusCreated, ""},
+		{"Water all the plants", http.StatusBadRequest, "Title has 4 words, must have at most 3"},
+		{strings.Repeat("é", 30), http.StatusCreated, ""},
+		{strings.Repeat("é", 31), http.StatusBadRequest, "Title has 31 characters, must have at most 30"},
+	}
+	for _, test := range tests {
+		rec := do(app, "POST", "/tasks", `{"Title":"`+test.title+`"}`)
+		if rec.Code != test.status {
+			t.Errorf("creating %q: got status %d, want %d", test.title, rec.Code, test.status)
+			continue
+		}
+		if test.error != "" {
+			var res errorResponse
+			decode(t, rec, &res)
+			if res.Error != test.error {
+				t.Errorf("creating %q: got error %q, want %q", test.title, res.Error, test.error)
+			}
+		}
+	}
+
+	// Edits are held to the same limits.
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"Title":"Water all the plants"}`), http.StatusBadRequest)
+	expectStatus(t, do(app, "PATCH", "/tasks/1", `{"Title":"Water plants"}`), http.StatusOK)
+}
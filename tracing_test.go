@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in
+// memory until the test ends.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTraceRequests(t *testing.T) {
+	exporter := recordSpans(t)
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+	expectStatus(t, do(app, "GET", "/tasks/1", ""), http.StatusOK)
+	expectStatus(t, do(app, "GET", "/tasks/99", ""), http.StatusNotFound)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want one per request", len(spans))
+	}
+	for index, want := range []struct {
+		name   string
+		status int64
+		taskId int64
+	}{
+		{"POST /tasks", http.StatusCreated, 0},
+		{"GET /tasks/", http.StatusOK, 1},
+		{"GET /tasks/", http.StatusNotFound, 99},
+	} {
+		span := spans[index]
+		if span.Name != want.name {
+			t.Errorf("span %d is named %q, want %q", index, span.Name, want.name)
+		}
+		if status, _ := spanAttribute(span, "http.status_code"); status.AsInt64() != want.status {
+			t.Errorf("span %d has status %v, want %d", index, status.Emit(), want.status)
+		}
+		taskId, ok := spanAttribute(span, "brain.task_id")
+		if want.taskId == 0 && ok || want.taskId != 0 && taskId.AsInt64() != want.taskId {
+			t.Errorf("span %d has task ID %v, want %d", index, taskId.Emit(), want.taskId)
+		}
+	}
+}
+
+func TestTraceRequestsMarksServerErrors(t *testing.T) {
+	exporter := recordSpans(t)
+	app, _ := newTestApp(t)
+	createTask(t, app, `{"Title":"Water plants"}`)
+	app.store = brokenStore{TaskStore: app.store, getErr: errors.New("disk on fire")}
+	expectStatus(t, do(app, "DELETE", "/tasks/1", ""), http.StatusInternalServerError)
+
+	spans := exporter.GetSpans()
+	if span := spans[len(spans)-1]; span.Status.Code != codes.Error {
+		t.Errorf("span for a 500 has status %v, want an error", span.Status)
+	}
+}